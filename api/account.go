@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/bytom/vapor/crypto/ed25519/chainkd"
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+)
+
+// aliasHashLen is the number of hex characters of the xpub hash in a generated
+// account alias.
+const aliasHashLen = 16
+
+// AccountAlias returns the account alias of rootXPub under prefix. It is the
+// same for every call with the same arguments and differs between xpubs, so
+// CreateAccount with it never collides with the account of another key.
+func AccountAlias(prefix, rootXPub string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(rootXPub)))
+	alias := hex.EncodeToString(hash[:])[:aliasHashLen]
+	if prefix != "" {
+		alias = prefix + "_" + alias
+	}
+	return normalizeAlias(alias)
+}
+
+func (s *ServerAdapter) CreateAccountWithPrefix(rootXPub, prefix string) (string, error) {
+	return s.CreateAccountWithPrefixContext(context.Background(), rootXPub, prefix)
+}
+
+// CreateAccountWithPrefixContext creates the account of rootXPub under the
+// alias AccountAlias(prefix, rootXPub), or returns it when it already exists.
+func (s *ServerAdapter) CreateAccountWithPrefixContext(ctx context.Context, rootXPub, prefix string) (string, error) {
+	return s.CreateAccountWithContext(ctx, rootXPub, AccountAlias(prefix, rootXPub))
+}
+
+func (s *ServerAdapter) CreateMultisigAccount(rootXPubs []string, quorum int, accountAlias string) (string, error) {
+	return s.CreateMultisigAccountWithContext(context.Background(), rootXPubs, quorum, accountAlias)
+}
+
+// CreateMultisigAccountWithContext creates the account accountAlias spendable
+// by quorum signatures of rootXPubs. The node derives its addresses from the
+// keys in the given order, see ClientAdapter.MultisigAddress. It returns the
+// account when the alias already names one of the same keys and quorum.
+func (s *ServerAdapter) CreateMultisigAccountWithContext(ctx context.Context, rootXPubs []string, quorum int, accountAlias string) (string, error) {
+	if _, err := accountXPubs(rootXPubs, quorum); err != nil {
+		return "", err
+	}
+
+	return s.createAccount(ctx, rootXPubs, quorum, accountAlias)
+}
+
+func (s *ServerAdapter) createAccount(ctx context.Context, rootXPubs []string, quorum int, accountAlias string) (string, error) {
+	req := &internal.CreateAccountReq{RootXpubs: rootXPubs, Quorum: quorum, Alias: accountAlias}
+	resp := &internal.CreateAccountResp{}
+	if err := s.call(ctx, "/create-account", req, resp, false); err != nil {
+		if !errors.Is(err, common.ErrDuplicateAlias) {
+			return "", errors.Wrapf(err, "request create account")
+		}
+
+		if resp, err = s.findAccount(ctx, &internal.ListAccountsReq{Alias: normalizeAlias(accountAlias)}, rootXPubs, quorum); err != nil {
+			return "", err
+		}
+		if resp == nil {
+			return "", errors.Wrapf(common.ErrDuplicateAlias, "alias %s belongs to an account of other keys", accountAlias)
+		}
+	}
+
+	return resp.AccountId, s.recordAccount(resp, rootXPubs)
+}
+
+// recordAccount records account in the account directory, if any.
+func (s *ServerAdapter) recordAccount(account *internal.CreateAccountResp, rootXPubs []string) error {
+	if s.directory == nil {
+		return nil
+	}
+	return s.directory.AddAccount(account.AccountId, account.Alias, rootXPubs)
+}
+
+// xpubAccount returns the account of the single key of rootXPubs recorded in
+// the account directory, or else listed by the node, empty when there is none.
+// Listing reads every account of the node.
+func (s *ServerAdapter) xpubAccount(ctx context.Context, rootXPubs []string) (string, error) {
+	if s.directory != nil {
+		if accountId, ok := s.directory.AccountForXPub(rootXPubs[0]); ok {
+			return accountId, nil
+		}
+	}
+
+	account, err := s.findAccount(ctx, &internal.ListAccountsReq{}, rootXPubs, 1)
+	if err != nil || account == nil {
+		return "", err
+	}
+	return account.AccountId, s.recordAccount(account, rootXPubs)
+}
+
+// findAccount returns the first account listed for req that is the account of
+// rootXPubs and quorum, nil when there is none.
+func (s *ServerAdapter) findAccount(ctx context.Context, req *internal.ListAccountsReq, rootXPubs []string, quorum int) (*internal.CreateAccountResp, error) {
+	var resp []*internal.Account
+	if err := s.call(ctx, "/list-accounts", req, &resp, true); err != nil {
+		return nil, errors.Wrapf(err, "request list accounts")
+	}
+
+	for _, account := range resp {
+		if account != nil && account.Quorum == quorum && sameXPubs(account.XPubs, rootXPubs) {
+			return &internal.CreateAccountResp{Alias: account.Alias, AccountId: account.ID}, nil
+		}
+	}
+	return nil, nil
+}
+
+// accountXPubs decodes the keys of an account of rootXPubs and quorum.
+func accountXPubs(rootXPubs []string, quorum int) ([]chainkd.XPub, error) {
+	if quorum < 1 || quorum > len(rootXPubs) {
+		return nil, common.ErrBadQuorum
+	}
+
+	var xPubs []chainkd.XPub
+	seen := make(map[chainkd.XPub]bool)
+	for _, rootXPub := range rootXPubs {
+		xPub, err := pubkeyToXPub(rootXPub)
+		if err != nil {
+			return nil, errors.Wrap(err, "pubkeyToXPub")
+		}
+
+		if seen[*xPub] {
+			return nil, errors.Wrapf(common.ErrDuplicateXPub, "%s", rootXPub)
+		}
+		seen[*xPub] = true
+		xPubs = append(xPubs, *xPub)
+	}
+	return xPubs, nil
+}
+
+func sameXPubs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeAlias returns alias as the node stores it.
+func normalizeAlias(alias string) string {
+	return strings.ToLower(strings.TrimSpace(alias))
+}
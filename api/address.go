@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/hex"
+
+	vaporCommon "github.com/bytom/vapor/common"
+	"github.com/bytom/vapor/common/bech32"
+	"github.com/bytom/vapor/consensus"
+	"github.com/bytom/vapor/protocol/vm/vmutil"
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+)
+
+// AddressType classifies an address checked by ValidateAddress.
+type AddressType int
+
+const (
+	// AddressMalformed is not a bech32 witness address, or has a bad checksum
+	// or witness program.
+	AddressMalformed AddressType = iota
+	// AddressWrongNetwork is a well-formed bech32 address of another network.
+	AddressWrongNetwork
+	// AddressP2WPKH pays to the hash of a single public key.
+	AddressP2WPKH
+	// AddressP2WSH pays to the hash of a script, such as a multi-signature one.
+	AddressP2WSH
+	// AddressEthereum is a 0x Ethereum address, the mainchain destination of
+	// the assets bridged from Ethereum.
+	AddressEthereum
+)
+
+// AddressInfo is the result of an address check.
+type AddressInfo struct {
+	Type AddressType
+	// ControlProgram is the hex control program paying to the address, empty
+	// when the address is invalid.
+	ControlProgram string
+}
+
+// ValidateAddress checks offline that address is a P2WPKH or P2WSH address of
+// the adapter's network. The returned info is never nil; the error, wrapping
+// common.ErrInvalidAddress, is set for every other type.
+func (c *ClientAdapter) ValidateAddress(address string) (*AddressInfo, error) {
+	addressType, program, err := decodeAddress(address, c.netParams)
+	return addressInfo(addressType, program), err
+}
+
+// ValidateMainchainAddress checks offline that address is a valid cross-chain
+// withdrawal destination of assetId: a 0x Ethereum address for the assets
+// bridged from Ethereum, a P2WPKH or P2WSH address of the Bytom mainchain
+// paired with the adapter's network otherwise. The control program of an
+// Ethereum address is the one of the cross-chain output.
+func (c *ClientAdapter) ValidateMainchainAddress(assetId, address string) (*AddressInfo, error) {
+	if isEthereumAsset(assetId) {
+		program, err := ethAddressToProgram(address)
+		if err != nil {
+			return addressInfo(AddressMalformed, nil), err
+		}
+		return addressInfo(AddressEthereum, program), nil
+	}
+
+	addressType, program, err := decodeAddress(address, consensus.BytomMainNetParams(c.netParams))
+	return addressInfo(addressType, program), err
+}
+
+func addressInfo(addressType AddressType, program []byte) *AddressInfo {
+	return &AddressInfo{Type: addressType, ControlProgram: hex.EncodeToString(program)}
+}
+
+// decodeAddress classifies address against the network of netParams and
+// returns the control program paying to it when it is valid.
+func decodeAddress(address string, netParams *consensus.Params) (AddressType, []byte, error) {
+	hrp, _, err := bech32.Bech32Decode(address)
+	if err != nil {
+		return AddressMalformed, nil, errors.Wrapf(common.ErrInvalidAddress, "%s: %v", address, err)
+	}
+
+	if hrp != netParams.Bech32HRPSegwit {
+		return AddressWrongNetwork, nil, errors.Wrapf(common.ErrInvalidAddress, "%s is not for %s", address, netParams.Name)
+	}
+
+	decoded, err := vaporCommon.DecodeAddress(address, netParams)
+	if err != nil {
+		return AddressMalformed, nil, errors.Wrapf(common.ErrInvalidAddress, "%s: %v", address, err)
+	}
+
+	var addressType AddressType
+	var program []byte
+	switch decoded.(type) {
+	case *vaporCommon.AddressWitnessPubKeyHash:
+		addressType = AddressP2WPKH
+		program, err = vmutil.P2WPKHProgram(decoded.ScriptAddress())
+	case *vaporCommon.AddressWitnessScriptHash:
+		addressType = AddressP2WSH
+		program, err = vmutil.P2WSHProgram(decoded.ScriptAddress())
+	default:
+		return AddressMalformed, nil, errors.Wrapf(common.ErrInvalidAddress, "%s has unsupported type", address)
+	}
+	if err != nil {
+		return AddressMalformed, nil, errors.Wrapf(common.ErrInvalidAddress, "%s: %v", address, err)
+	}
+	return addressType, program, nil
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+	"vapor-adapter/types"
+)
+
+// TokenRegistry returns the registry the adapter resolves assets through, so
+// it can be shared with a ClientAdapter or extended at runtime.
+func (s *ServerAdapter) TokenRegistry() *common.TokenRegistry {
+	return s.tokens
+}
+
+// LoadAssets registers every asset known by the node's list-assets endpoint
+// and returns how many were registered.
+func (s *ServerAdapter) LoadAssets(ctx context.Context) (int, error) {
+	var resp []*internal.Asset
+	if err := s.call(ctx, "/list-assets", &internal.GetAssetReq{}, &resp, true); err != nil {
+		return 0, errors.Wrapf(err, "request list assets")
+	}
+
+	count := 0
+	for _, asset := range resp {
+		param, ok := assetToTokenParam(asset)
+		if !ok {
+			continue
+		}
+
+		s.tokens.Register(asset.ID, param)
+		count++
+	}
+	return count, nil
+}
+
+// LoadAsset fetches assetId from the node's get-asset endpoint and registers it.
+func (s *ServerAdapter) LoadAsset(ctx context.Context, assetId string) (common.TokenParam, error) {
+	resp := &internal.Asset{}
+	if err := s.call(ctx, "/get-asset", &internal.GetAssetReq{ID: assetId}, resp, true); err != nil {
+		return common.TokenParam{}, errors.Wrapf(err, "request get asset")
+	}
+
+	param, ok := assetToTokenParam(resp)
+	if !ok {
+		return common.TokenParam{}, errors.Errorf("asset %s has no decimals in its definition", assetId)
+	}
+
+	s.tokens.Register(resp.ID, param)
+	return param, nil
+}
+
+// assetToTokenParam reads the code and decimals out of an asset definition,
+// preferring the symbol, then the name and finally the alias as the code.
+func assetToTokenParam(asset *internal.Asset) (common.TokenParam, bool) {
+	definition := &struct {
+		Decimals *uint8 `json:"decimals"`
+		Symbol   string `json:"symbol"`
+		Name     string `json:"name"`
+	}{}
+	if err := json.Unmarshal(asset.Definition, definition); err != nil || definition.Decimals == nil {
+		return common.TokenParam{}, false
+	}
+
+	code := definition.Symbol
+	if code == "" {
+		code = definition.Name
+	}
+	if code == "" {
+		code = strings.ToUpper(asset.Alias)
+	}
+	return common.TokenParam{Code: code, Decimal: *definition.Decimals}, true
+}
+
+// assetMisses remembers when the node last failed to resolve an asset.
+type assetMisses struct {
+	mu       sync.Mutex
+	failedAt map[string]time.Time
+}
+
+// recent reports whether assetId failed less than interval ago.
+func (m *assetMisses) recent(assetId string, interval time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	failedAt, ok := m.failedAt[assetId]
+	return ok && time.Since(failedAt) < interval
+}
+
+func (m *assetMisses) add(assetId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failedAt[assetId] = time.Now()
+}
+
+// loadUnknownAssets looks up every distinct asset of assetIds missing from the
+// registry. Assets the node cannot resolve are left unknown, reported to the
+// asset lookup observer and not asked for again within the retry interval.
+func (s *ServerAdapter) loadUnknownAssets(ctx context.Context, assetIds []string) {
+	seen := make(map[string]bool)
+	for _, assetId := range assetIds {
+		if seen[assetId] {
+			continue
+		}
+		seen[assetId] = true
+
+		if _, ok := s.tokens.Get(assetId); ok || s.assetMisses.recent(assetId, s.assetRetry) {
+			continue
+		}
+
+		if _, err := s.LoadAsset(ctx, assetId); err != nil {
+			// a cancelled lookup says nothing about the asset
+			if ctx.Err() != nil {
+				return
+			}
+
+			s.assetMisses.add(assetId)
+			if s.assetObserver != nil {
+				s.assetObserver(assetId, err)
+			}
+		}
+	}
+}
+
+// newUTXO returns the UTXO moving amount of assetId, or nil when the asset is
+// not registered and mode drops unknown assets.
+func newUTXO(tokens *common.TokenRegistry, mode common.UnknownAssetMode, address string, amount uint64, assetId string) *types.UTXO {
+	tokenParams, ok := tokens.Get(assetId)
+	if !ok && mode == common.UnknownAssetDrop {
+		//ignore invalid token
+		return nil
+	}
+
+	return &types.UTXO{
+		Address:         address,
+		Value:           amount,
+		TokenIdentifier: assetId,
+		TokenCode:       tokenParams.Code,
+		TokenDecimal:    tokenParams.Decimal,
+		Unknown:         !ok,
+	}
+}
@@ -0,0 +1,356 @@
+package api
+
+import (
+	"context"
+	"sort"
+
+	"github.com/bytom/vapor/math/checked"
+	vaporTypes "github.com/bytom/vapor/protocol/bc/types"
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+	"vapor-adapter/types"
+)
+
+func (s *ServerAdapter) BuildBatchTransaction(accountId string, payments []*types.Payment) ([]*internal.BuildTransactionResp, error) {
+	return s.BuildBatchTransactionWithContext(context.Background(), accountId, payments)
+}
+
+// BuildBatchTransactionWithContext pays every payment from accountId with as
+// few transactions as the adapter's batch limits allow. Each transaction has a
+// single spend action per asset. The transactions are planned before anything
+// is built, see planBatch, so an account that can not pay every transaction
+// and its fee fails with common.ErrInsufficientFunds without reserving any
+// UTXO. Built without a fee policy the transactions pay no fee, see FeeNone.
+// When a build fails part way, the templates built so far are returned along
+// with a *common.BatchError, so their reserved UTXOs can be submitted or
+// waited out.
+func (s *ServerAdapter) BuildBatchTransactionWithContext(ctx context.Context, accountId string, payments []*types.Payment) ([]*internal.BuildTransactionResp, error) {
+	return s.BuildBatchTransactionWithOptionsContext(ctx, accountId, payments, nil)
+}
+
+func (s *ServerAdapter) BuildBatchTransactionWithOptions(accountId string, payments []*types.Payment, opts *BuildOptions) ([]*internal.BuildTransactionResp, error) {
+	return s.BuildBatchTransactionWithOptionsContext(context.Background(), accountId, payments, opts)
+}
+
+// BuildBatchTransactionWithOptionsContext is BuildBatchTransactionWithContext
+// building every transaction with opts. A base transaction can not be shared
+// by several transactions, so opts must not have one.
+func (s *ServerAdapter) BuildBatchTransactionWithOptionsContext(ctx context.Context, accountId string, payments []*types.Payment, opts *BuildOptions) ([]*internal.BuildTransactionResp, error) {
+	if opts != nil && opts.BaseTransaction != "" {
+		return nil, errors.Wrap(common.ErrBadBuildOptions, "batch builds take no base transaction")
+	}
+
+	if err := s.checkBuildOptions(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	chunks, err := s.planBatch(ctx, accountId, payments, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var built []*internal.BuildTransactionResp
+	for _, chunk := range chunks {
+		tpl, err := s.buildBatchTransaction(ctx, accountId, payments[chunk.start:chunk.end], opts)
+		if err != nil {
+			batchErr := &common.BatchError{Built: built, Err: errors.Wrapf(err, "build payments %d to %d", chunk.start, chunk.end-1)}
+			if tpl != nil {
+				batchErr.Oversized = append(batchErr.Oversized, tpl)
+			}
+			return built, batchErr
+		}
+		built = append(built, tpl)
+	}
+	return built, nil
+}
+
+// batchChunk is the range of payments paid by one transaction of a batch.
+type batchChunk struct {
+	start, end int
+}
+
+// planBatch splits payments into the transactions of a batch. Each takes as
+// many payments as the output limit allows, halved until the UTXOs the node
+// picks for them and their fee fit the input limit. The UTXOs picked for a
+// transaction are set aside for the following ones, as the node reserves
+// them, so the plan covers the balance of the whole batch. UTXOs reserved by
+// other builds still pending are not known to the plan.
+func (s *ServerAdapter) planBatch(ctx context.Context, accountId string, payments []*types.Payment, opts *BuildOptions) ([]batchChunk, error) {
+	totals, err := paymentTotals(payments)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := s.utxoPool(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	// every transaction may need a change output per asset
+	chunkSize := len(payments)
+	if s.maxTxOutputs > 0 {
+		chunkSize = s.maxTxOutputs - len(totals)
+		if chunkSize < 1 {
+			chunkSize = 1
+		}
+	}
+
+	mergedFee, separateFee := batchFee(opts)
+	var chunks []batchChunk
+	for start := 0; start < len(payments); {
+		end := start + chunkSize
+		if end > len(payments) {
+			end = len(payments)
+		}
+
+		for {
+			spends, err := batchSpends(payments[start:end], mergedFee, separateFee)
+			if err != nil {
+				return nil, err
+			}
+
+			left, inputs, err := pool.spend(spends)
+			if err != nil {
+				return nil, errors.Wrapf(err, "payments %d to %d", start, end-1)
+			}
+
+			if s.maxTxInputs <= 0 || inputs <= s.maxTxInputs {
+				pool = left
+				break
+			}
+			if end-start == 1 {
+				return nil, errors.Wrapf(common.ErrTooManyInputs, "payment %d needs %d inputs", start, inputs)
+			}
+			end = start + (end-start)/2
+		}
+
+		chunks = append(chunks, batchChunk{start: start, end: end})
+		start = end
+	}
+	return chunks, nil
+}
+
+// batchFee returns the fee every transaction of a batch built with opts pays:
+// a fixed fee is merged by the node into the BTM spend of the payments, an
+// estimated one is spent by a build of its own and only known up to its
+// MaxFee.
+func batchFee(opts *BuildOptions) (merged, separate uint64) {
+	if opts == nil || opts.Fee == nil {
+		return 0, 0
+	}
+
+	switch opts.Fee.Mode {
+	case FeeFixed:
+		return opts.Fee.Amount, 0
+	case FeeEstimated, FeeEstimatedWithMargin:
+		return 0, opts.Fee.MaxFee
+	}
+	return 0, 0
+}
+
+// batchSpends returns the spends the node reserves UTXOs for to pay payments
+// and the fees of batchFee, one per asset and one for a separate fee.
+func batchSpends(payments []*types.Payment, mergedFee, separateFee uint64) ([]assetSpend, error) {
+	totals, err := paymentTotals(payments)
+	if err != nil {
+		return nil, err
+	}
+
+	if mergedFee > 0 {
+		total, ok := checked.AddUint64(totals[common.BTM], mergedFee)
+		if !ok {
+			return nil, errors.Wrap(common.ErrBadPayment, "total with fee overflows")
+		}
+		totals[common.BTM] = total
+	}
+
+	var assetIds []string
+	for assetId := range totals {
+		assetIds = append(assetIds, assetId)
+	}
+	sort.Strings(assetIds)
+
+	var spends []assetSpend
+	for _, assetId := range assetIds {
+		spends = append(spends, assetSpend{assetId: assetId, amount: totals[assetId]})
+	}
+	if separateFee > 0 {
+		spends = append(spends, assetSpend{assetId: common.BTM, amount: separateFee})
+	}
+	return spends, nil
+}
+
+// buildBatchTransaction builds the transaction paying payments. It returns the
+// template along with ErrTooManyInputs when the node picked more inputs than
+// the adapter allows.
+func (s *ServerAdapter) buildBatchTransaction(ctx context.Context, accountId string, payments []*types.Payment, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	totals, err := paymentTotals(payments)
+	if err != nil {
+		return nil, err
+	}
+
+	var assetIds []string
+	for assetId := range totals {
+		assetIds = append(assetIds, assetId)
+	}
+	sort.Strings(assetIds)
+
+	var actions []*internal.Actions
+	for _, assetId := range assetIds {
+		actions = append(actions, &internal.Actions{AccountId: accountId, Amount: totals[assetId], AssetId: assetId, Type: "spend_account"})
+	}
+	for _, payment := range payments {
+		actions = append(actions, &internal.Actions{Amount: payment.Amount, AssetId: payment.AssetID, Type: "control_address", Address: payment.Address})
+	}
+
+	tpl, err := s.buildWithOptions(ctx, accountId, actions, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &vaporTypes.Tx{}
+	if err := tx.UnmarshalText([]byte(tpl.RawTransaction)); err != nil {
+		return nil, errors.Wrap(err, "unmarshal raw transaction")
+	}
+
+	if s.maxTxInputs > 0 && len(tx.Inputs) > s.maxTxInputs {
+		return tpl, errors.Wrapf(common.ErrTooManyInputs, "%d inputs", len(tx.Inputs))
+	}
+	return tpl, nil
+}
+
+// paymentTotals validates payments and sums their amounts per asset.
+func paymentTotals(payments []*types.Payment) (map[string]uint64, error) {
+	if len(payments) == 0 {
+		return nil, common.ErrNoPayments
+	}
+
+	totals := make(map[string]uint64)
+	for i, payment := range payments {
+		if payment.Address == "" || payment.AssetID == "" || payment.Amount == 0 {
+			return nil, errors.Wrapf(common.ErrBadPayment, "payment %d", i)
+		}
+
+		total, ok := checked.AddUint64(totals[payment.AssetID], payment.Amount)
+		if !ok {
+			return nil, errors.Wrapf(common.ErrBadPayment, "total of %s overflows", payment.AssetID)
+		}
+		totals[payment.AssetID] = total
+	}
+	return totals, nil
+}
+
+// desireUTXOCount is the number of UTXOs the node tries to spend for an
+// amount when it can replace a large UTXO by smaller ones.
+const desireUTXOCount = 5
+
+// assetSpend is an amount of an asset reserved by a single spend action.
+type assetSpend struct {
+	assetId string
+	amount  uint64
+}
+
+// utxoPool holds the amounts of the spendable UTXOs of an account per asset,
+// largest first.
+type utxoPool map[string][]uint64
+
+// utxoPool returns the UTXOs of accountId the node may spend, leaving out
+// the immature ones.
+func (s *ServerAdapter) utxoPool(ctx context.Context, accountId string) (utxoPool, error) {
+	req := &internal.ListUnspentReq{AccountId: accountId}
+	var resp []*internal.UnspentOutput
+	if err := s.call(ctx, "/list-unspent-outputs", req, &resp, true); err != nil {
+		return nil, errors.Wrapf(err, "request list unspent outputs")
+	}
+
+	var bestHeight uint64
+	for _, utxo := range resp {
+		if utxo.ValidHeight > 0 {
+			height, err := s.GetBlockCountWithContext(ctx)
+			if err != nil {
+				return nil, errors.Wrap(err, "request block count")
+			}
+			bestHeight = height
+			break
+		}
+	}
+
+	pool := make(utxoPool)
+	for _, utxo := range resp {
+		if utxo.ValidHeight > bestHeight {
+			continue
+		}
+		pool[utxo.AssetId] = append(pool[utxo.AssetId], utxo.Amount)
+	}
+
+	for _, amounts := range pool {
+		sort.Slice(amounts, func(i, j int) bool { return amounts[i] > amounts[j] })
+	}
+	return pool, nil
+}
+
+// spend picks the UTXOs the node would reserve for spends, in order, and
+// returns the pool left without them and the number of UTXOs picked.
+func (p utxoPool) spend(spends []assetSpend) (utxoPool, int, error) {
+	left := make(utxoPool, len(p))
+	for assetId, amounts := range p {
+		left[assetId] = amounts
+	}
+
+	inputs := 0
+	for _, spend := range spends {
+		amounts := left[spend.assetId]
+		picked, ok := selectUTXOs(amounts, spend.amount)
+		if !ok {
+			return nil, 0, errors.Wrapf(common.ErrInsufficientFunds, "%d of %s", spend.amount, spend.assetId)
+		}
+
+		remaining := make([]uint64, 0, len(amounts)-len(picked))
+		for i, j := 0, 0; i < len(amounts); i++ {
+			if j < len(picked) && picked[j] == i {
+				j++
+				continue
+			}
+			remaining = append(remaining, amounts[i])
+		}
+		left[spend.assetId] = remaining
+		inputs += len(picked)
+	}
+	return left, inputs, nil
+}
+
+// selectUTXOs picks UTXOs out of amounts, sorted largest first, to pay amount
+// the way the UTXO keeper of the node does: the largest ones until amount is
+// covered, then smaller ones in place of the largest picked while that keeps
+// the count around desireUTXOCount. The picked indexes are in order.
+func selectUTXOs(amounts []uint64, amount uint64) ([]int, bool) {
+	var picked []int
+	var pickedAmount uint64
+	for i := 0; i < len(amounts); i++ {
+		if pickedAmount < amount {
+			picked = append(picked, i)
+			pickedAmount += amounts[i]
+			continue
+		}
+
+		var replace []int
+		replaceAmount := pickedAmount - amounts[picked[0]]
+		replaced := false
+		for ; i < len(amounts) && len(replace) <= desireUTXOCount-len(picked); i++ {
+			replace = append(replace, i)
+			if replaceAmount += amounts[i]; replaceAmount >= amount {
+				picked = append(picked[1:], replace...)
+				pickedAmount = replaceAmount
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			break
+		}
+	}
+	return picked, pickedAmount >= amount
+}
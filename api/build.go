@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	vaporTypes "github.com/bytom/vapor/protocol/bc/types"
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+)
+
+// MaxBuildTTL is the longest time BuildOptions may ask the node to reserve the
+// spent UTXOs for.
+const MaxBuildTTL = 24 * time.Hour
+
+// BuildOptions tune a transaction built by the node. A nil or zero BuildOptions
+// builds with the node defaults.
+type BuildOptions struct {
+	// TTL is how long the node reserves the spent UTXOs, in whole
+	// milliseconds. Zero keeps the node default of 30 minutes.
+	TTL time.Duration
+	// TimeRange is the last block height the transaction can be packed at.
+	// Zero keeps it valid forever.
+	TimeRange uint64
+	// BaseTransaction is a raw transaction the built actions are added to.
+	BaseTransaction string
+	// Fee is the fee paid by the transaction. A nil Fee is FeeNone and pays
+	// no fee.
+	Fee *FeePolicy
+}
+
+// Validate checks the options that do not need the node.
+func (o *BuildOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	if o.TTL < 0 || o.TTL%time.Millisecond != 0 || o.TTL > MaxBuildTTL {
+		return errors.Wrapf(common.ErrBadBuildOptions, "ttl %s is not whole milliseconds up to %s", o.TTL, MaxBuildTTL)
+	}
+
+	if o.BaseTransaction != "" {
+		if err := (&vaporTypes.TxData{}).UnmarshalText([]byte(o.BaseTransaction)); err != nil {
+			return errors.Wrapf(common.ErrBadBuildOptions, "base transaction: %v", err)
+		}
+	}
+	return o.Fee.validate()
+}
+
+func (s *ServerAdapter) BuildTransactionWithOptions(accountId, toAddress, tokenIdentifier string, amount uint64, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	return s.BuildTransactionWithOptionsContext(context.Background(), accountId, toAddress, tokenIdentifier, amount, opts)
+}
+
+// BuildTransactionWithOptionsContext is BuildTransactionWithContext built with
+// opts.
+func (s *ServerAdapter) BuildTransactionWithOptionsContext(ctx context.Context, accountId, toAddress, tokenIdentifier string, amount uint64, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	if err := s.checkBuildOptions(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	return s.buildWithOptions(ctx, accountId, transferActions(accountId, toAddress, tokenIdentifier, amount), opts)
+}
+
+// checkBuildOptions validates opts, checking the time range against the
+// current chain height.
+func (s *ServerAdapter) checkBuildOptions(ctx context.Context, opts *BuildOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	if opts == nil || opts.TimeRange == 0 {
+		return nil
+	}
+
+	height, err := s.GetBlockCountWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if opts.TimeRange <= height {
+		return errors.Wrapf(common.ErrBadBuildOptions, "time range %d is not above the current height %d", opts.TimeRange, height)
+	}
+	return nil
+}
+
+// buildWithOptions builds actions paid by accountId with opts checked by
+// checkBuildOptions.
+func (s *ServerAdapter) buildWithOptions(ctx context.Context, accountId string, actions []*internal.Actions, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	req := &internal.BuildTransactionReq{Actions: actions}
+	if opts == nil {
+		return s.buildTransaction(ctx, req)
+	}
+
+	req.TTL = int(opts.TTL / time.Millisecond)
+	req.TimeRange = opts.TimeRange
+	if opts.BaseTransaction != "" {
+		req.BaseTransaction = opts.BaseTransaction
+	}
+
+	return s.buildWithFee(ctx, accountId, req, opts.Fee)
+}
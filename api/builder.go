@@ -0,0 +1,204 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+
+	"github.com/bytom/vapor/crypto"
+	"github.com/bytom/vapor/crypto/ed25519/chainkd"
+	"github.com/bytom/vapor/math/checked"
+	"github.com/bytom/vapor/protocol/bc"
+	vaporTypes "github.com/bytom/vapor/protocol/bc/types"
+	"github.com/bytom/vapor/protocol/vm/vmutil"
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+	"vapor-adapter/types"
+)
+
+// BuildTransaction assembles an unsigned transaction offline that spends
+// exactly the given unspent outputs, pays every payment, takes fee in BTM and
+// sends whatever is left of each asset to changeAddress. The result carries
+// the signing instructions expected by SignTransaction and the node's
+// sign-transaction. Payments are checked like the ones of
+// ServerAdapter.BuildBatchTransaction, and must pay to valid addresses of the
+// adapter's network.
+func (c *ClientAdapter) BuildTransaction(unspents []*types.Unspent, payments []*types.Payment, changeAddress string, fee uint64) (*internal.BuildTransactionResp, error) {
+	if _, err := paymentTotals(payments); err != nil {
+		return nil, err
+	}
+
+	txData := &vaporTypes.TxData{Version: 1}
+	var signingInsts []internal.SigningInstructions
+	balances := make(map[string]uint64)
+	for i, unspent := range unspents {
+		txInput, signingInst, err := c.unspentToInput(unspent)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unspent output %d", i)
+		}
+
+		signingInst.Position = i
+		txData.Inputs = append(txData.Inputs, txInput)
+		signingInsts = append(signingInsts, *signingInst)
+		sum, ok := checked.AddUint64(balances[unspent.AssetID], unspent.Amount)
+		if !ok {
+			return nil, errors.Wrapf(common.ErrInsufficientUTXO, "amount of %s overflows", unspent.AssetID)
+		}
+		balances[unspent.AssetID] = sum
+	}
+
+	spend := func(assetId string, amount uint64) error {
+		left, ok := checked.SubUint64(balances[assetId], amount)
+		if !ok {
+			return errors.Wrapf(common.ErrInsufficientUTXO, "asset %s", assetId)
+		}
+		balances[assetId] = left
+		return nil
+	}
+
+	for i, payment := range payments {
+		txOutput, err := c.paymentToOutput(payment)
+		if err != nil {
+			return nil, errors.Wrapf(err, "payment %d", i)
+		}
+
+		if err := spend(payment.AssetID, payment.Amount); err != nil {
+			return nil, err
+		}
+		txData.Outputs = append(txData.Outputs, txOutput)
+	}
+
+	if err := spend(common.BTM, fee); err != nil {
+		return nil, errors.Wrap(err, "fee")
+	}
+
+	var assetIds []string
+	for assetId, amount := range balances {
+		if amount > 0 {
+			assetIds = append(assetIds, assetId)
+		}
+	}
+	sort.Strings(assetIds)
+	for _, assetId := range assetIds {
+		change := &types.Payment{Address: changeAddress, AssetID: assetId, Amount: balances[assetId]}
+		txOutput, err := c.paymentToOutput(change)
+		if err != nil {
+			return nil, errors.Wrap(err, "change")
+		}
+		txData.Outputs = append(txData.Outputs, txOutput)
+	}
+
+	rawTx, err := vaporTypes.NewTx(*txData).MarshalText()
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal transaction")
+	}
+	return &internal.BuildTransactionResp{RawTransaction: string(rawTx), SigningInstructions: signingInsts, Fee: fee}, nil
+}
+
+// unspentToInput builds the spend input of unspent and its signing
+// instruction, checking that the account keys really control the output.
+func (c *ClientAdapter) unspentToInput(unspent *types.Unspent) (*vaporTypes.TxInput, *internal.SigningInstructions, error) {
+	var sourceID bc.Hash
+	if err := sourceID.UnmarshalText([]byte(unspent.SourceID)); err != nil {
+		return nil, nil, errors.Wrap(err, "decode source id")
+	}
+
+	var assetID bc.AssetID
+	if err := assetID.UnmarshalText([]byte(unspent.AssetID)); err != nil {
+		return nil, nil, errors.Wrap(err, "decode asset id")
+	}
+
+	controlProgram, err := hex.DecodeString(unspent.ControlProgram)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "decode control program")
+	}
+
+	path := pathForAddress(unspent.AccountIndex, unspent.AddressIndex, unspent.Change)
+	keys, witnessData, program, err := accountProgram(unspent.RootXPubs, unspent.Quorum, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !bytes.Equal(program, controlProgram) {
+		return nil, nil, common.ErrProgramMismatch
+	}
+
+	txInput := vaporTypes.NewSpendInput(nil, sourceID, assetID, unspent.Amount, unspent.SourcePos, controlProgram)
+	if unspent.OutputID != "" {
+		outputID, err := txInput.SpentOutputID()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "compute output id")
+		}
+
+		if outputID.String() != unspent.OutputID {
+			return nil, nil, common.ErrOutputIDMismatch
+		}
+	}
+
+	signingInst := &internal.SigningInstructions{
+		WitnessComponents: []*internal.WitnessComponent{
+			{Type: "raw_tx_signature", Quorum: unspent.Quorum, Keys: keys, Signatures: make([]string, len(keys))},
+			{Type: "data", Value: hex.EncodeToString(witnessData)},
+		},
+	}
+	return txInput, signingInst, nil
+}
+
+// accountProgram derives the control program of the account made of rootXPubs
+// and quorum at path, along with the signing keys and the data witness that
+// unlocks it: the derived public key for a single key account (P2WPKH) and
+// the multi-signature script otherwise (P2WSH).
+func accountProgram(rootXPubs []string, quorum int, path [][]byte) ([]*internal.KeyID, []byte, []byte, error) {
+	xPubs, err := accountXPubs(rootXPubs, quorum)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var keys []*internal.KeyID
+	for _, xPub := range xPubs {
+		keys = append(keys, &internal.KeyID{Xpub: xPub.String(), DerivationPath: encodeDerivationPath(path)})
+	}
+
+	derivedPKs := chainkd.XPubKeys(chainkd.DeriveXPubs(xPubs, path))
+	if len(derivedPKs) == 1 {
+		program, err := vmutil.P2WPKHProgram(crypto.Ripemd160(derivedPKs[0]))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return keys, derivedPKs[0], program, nil
+	}
+
+	script, err := vmutil.P2SPMultiSigProgram(derivedPKs, quorum)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	program, err := vmutil.P2WSHProgram(crypto.Sha256(script))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return keys, script, program, nil
+}
+
+// paymentToOutput builds the output paying payment to its address.
+func (c *ClientAdapter) paymentToOutput(payment *types.Payment) (*vaporTypes.TxOutput, error) {
+	var assetID bc.AssetID
+	if err := assetID.UnmarshalText([]byte(payment.AssetID)); err != nil {
+		return nil, errors.Wrap(err, "decode asset id")
+	}
+
+	controlProgram, err := c.addressToProgram(payment.Address)
+	if err != nil {
+		return nil, err
+	}
+	return vaporTypes.NewIntraChainOutput(assetID, payment.Amount, controlProgram), nil
+}
+
+// addressToProgram returns the control program paying to a P2WPKH or P2WSH
+// address of the adapter's network.
+func (c *ClientAdapter) addressToProgram(address string) ([]byte, error) {
+	_, program, err := decodeAddress(address, c.netParams)
+	return program, err
+}
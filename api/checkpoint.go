@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CheckpointStore persists the Checkpoint of a BlockScanner across restarts.
+// Save must replace the stored checkpoint atomically, so that a crash leaves
+// either the old or the new one.
+type CheckpointStore interface {
+	// Load returns the stored checkpoint, nil when none was saved yet.
+	Load() (*Checkpoint, error)
+	Save(checkpoint Checkpoint) error
+}
+
+// MemoryCheckpointStore keeps the checkpoint in memory, for tests and for
+// scanners that can rescan from a fixed height on every start.
+type MemoryCheckpointStore struct {
+	mu         sync.Mutex
+	checkpoint *Checkpoint
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{}
+}
+
+func (m *MemoryCheckpointStore) Load() (*Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.checkpoint == nil {
+		return nil, nil
+	}
+
+	checkpoint := *m.checkpoint
+	return &checkpoint, nil
+}
+
+func (m *MemoryCheckpointStore) Save(checkpoint Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checkpoint = &checkpoint
+	return nil
+}
+
+// FileCheckpointStore keeps the checkpoint as JSON in a file. Save writes a
+// temporary file next to it and renames it over the old one.
+type FileCheckpointStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+func (f *FileCheckpointStore) Load() (*Checkpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "read checkpoint file")
+	}
+
+	checkpoint := &Checkpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, errors.Wrapf(err, "decode checkpoint file %s", f.path)
+	}
+	return checkpoint, nil
+}
+
+func (f *FileCheckpointStore) Save(checkpoint Checkpoint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return errors.Wrap(err, "encode checkpoint")
+	}
+
+	return writeFileAtomic(f.path, data)
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file in
+// the same directory, then syncs the directory so the rename survives a crash.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "write temporary file")
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "sync temporary file")
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "close temporary file")
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "rename temporary file")
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes the entries of dir to disk. Windows can not sync a
+// directory and makes renames durable on its own.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap(err, "open directory")
+	}
+	defer d.Close()
+
+	return errors.Wrap(d.Sync(), "sync directory")
+}
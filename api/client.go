@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"math"
 
 	vaporCommon "github.com/bytom/vapor/common"
 	"github.com/bytom/vapor/consensus"
@@ -11,23 +12,30 @@ import (
 	"github.com/bytom/vapor/crypto"
 	"github.com/bytom/vapor/crypto/ed25519/chainkd"
 	"github.com/bytom/vapor/crypto/ed25519/ecmath"
-	"github.com/bytom/vapor/errors"
 	vaporTypes "github.com/bytom/vapor/protocol/bc/types"
+	"github.com/pkg/errors"
 
 	"vapor-adapter/common"
 	"vapor-adapter/types"
 )
 
 type ClientAdapter struct {
-	netParams *consensus.Params
+	netParams     *consensus.Params
+	tokens        *common.TokenRegistry
+	unknownAssets common.UnknownAssetMode
 }
 
-func NewClientAdapter(chainId string) (*ClientAdapter, error) {
+func NewClientAdapter(chainId string, opts ...ClientOption) (*ClientAdapter, error) {
 	netParams, ok := consensus.NetParams[chainId]
 	if !ok {
 		return nil, errors.New(fmt.Sprintf("%s does not exist", chainId))
 	}
-	return &ClientAdapter{netParams: &netParams}, nil
+
+	c := &ClientAdapter{netParams: &netParams, tokens: common.NewTokenRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
 }
 
 func (c *ClientAdapter) Deserialize(rawTxHex string) (*types.Tx, error) {
@@ -37,25 +45,28 @@ func (c *ClientAdapter) Deserialize(rawTxHex string) (*types.Tx, error) {
 	}
 
 	var decodedInputs []*types.UTXO
-	for _, input := range decodeTx.Inputs {
+	for i, input := range decodeTx.Inputs {
 		decodedInput, err := c.decodeTxInput(input)
 		if err != nil {
 			return nil, errors.Wrap(err, "decodeTxInput")
 		}
 
 		if decodedInput != nil {
+			decodedInput.Index = i
 			decodedInputs = append(decodedInputs, decodedInput)
 		}
 	}
 
 	var decodedOutputs []*types.UTXO
-	for _, output := range decodeTx.Outputs {
+	for i, output := range decodeTx.Outputs {
 		decodedOutput, err := c.decodeTxOutput(output)
 		if err != nil {
 			return nil, errors.Wrap(err, "decodeTxInput")
 		}
 
 		if decodedOutput != nil {
+			decodedOutput.Index = i
+			decodedOutput.OutputID = decodeTx.OutputID(i).String()
 			decodedOutputs = append(decodedOutputs, decodedOutput)
 		}
 
@@ -71,34 +82,79 @@ func (c *ClientAdapter) UnsignedTxHash(rawUnsignedTxHex string) (string, error)
 	return decodeTx.ID.String(), nil
 }
 
+// PubkeyToAddress returns the first address of the first account of the single
+// key pubkey, see DeriveAddress.
 func (c *ClientAdapter) PubkeyToAddress(pubkey string) (string, error) {
-	var xPubs []chainkd.XPub
-	xPub, err := pubkeyToXPub(pubkey)
+	return c.DeriveAddress(pubkey, 1, 1, false)
+}
+
+// DeriveAddress derives offline the P2WPKH address of the single key account
+// of rootXPub at the BIP44 path m/44/153/accountIndex/change/addressIndex the
+// node uses. The node counts both indexes from 1, and only credits an address
+// to the account once it created that receiver itself.
+func (c *ClientAdapter) DeriveAddress(rootXPub string, accountIndex, addressIndex uint64, change bool) (string, error) {
+	addresses, err := c.DeriveAddresses(rootXPub, accountIndex, addressIndex, 1, change)
 	if err != nil {
-		return "", errors.Wrap(err, "pubkeyToXPub")
+		return "", err
+	}
+
+	return addresses[0], nil
+}
+
+// DeriveAddresses derives the count addresses of DeriveAddress from address
+// index fromIndex on.
+func (c *ClientAdapter) DeriveAddresses(rootXPub string, accountIndex, fromIndex uint64, count int, change bool) ([]string, error) {
+	if count < 1 || uint64(count) > math.MaxUint32+1 || accountIndex > math.MaxUint32 || fromIndex > math.MaxUint32-uint64(count-1) {
+		return nil, errors.Wrapf(common.ErrBadDerivationIndex, "account %d, %d addresses from %d", accountIndex, count, fromIndex)
 	}
-	xPubs = append(xPubs, *xPub)
-	path := pathForAddress(1, 1, false)
-	derivedXPubs := chainkd.DeriveXPubs(xPubs, path)
-	derivedPKs := chainkd.XPubKeys(derivedXPubs)
 
-	pubHash := crypto.Ripemd160(derivedPKs[0])
-	address, err := vaporCommon.NewAddressWitnessPubKeyHash(pubHash, c.netParams)
+	xPub, err := pubkeyToXPub(rootXPub)
 	if err != nil {
-		return "", errors.Wrap(err, "NewAddressWitnessPubKeyHash")
+		return nil, errors.Wrap(err, "pubkeyToXPub")
 	}
-	return address.String(), nil
+
+	path := pathForAddress(accountIndex, fromIndex, change)
+	accountXPub := xPub.Derive(path[:len(path)-1])
+
+	var addresses []string
+	for i := 0; i < count; i++ {
+		index := make([]byte, 4)
+		binary.LittleEndian.PutUint32(index, uint32(fromIndex)+uint32(i))
+		pubHash := crypto.Ripemd160(accountXPub.Child(index).PublicKey())
+		address, err := vaporCommon.NewAddressWitnessPubKeyHash(pubHash, c.netParams)
+		if err != nil {
+			return nil, errors.Wrap(err, "NewAddressWitnessPubKeyHash")
+		}
+
+		addresses = append(addresses, address.String())
+	}
+	return addresses, nil
+}
+
+// MultisigAddress derives offline the address a node gives the account of
+// rootXPubs and quorum at the BIP44 account and address indexes: P2WSH for
+// several keys, P2WPKH for one. The account index counts the accounts created
+// on the same keys from 1.
+func (c *ClientAdapter) MultisigAddress(rootXPubs []string, quorum int, accountIndex, addressIndex uint64, change bool) (string, error) {
+	path := pathForAddress(accountIndex, addressIndex, change)
+	_, _, program, err := accountProgram(rootXPubs, quorum, path)
+	if err != nil {
+		return "", err
+	}
+
+	return c.scriptToAddress(program)
 }
 
 func (c *ClientAdapter) decodeTxInput(input *vaporTypes.TxInput) (*types.UTXO, error) {
 	assetId := input.AssetAmount().AssetId.String()
 	amount := input.AssetAmount().Amount
 
-	var address string
+	var address, utxoType, vote string
 	var err error
 
 	switch i := input.TypedInput.(type) {
 	case *vaporTypes.SpendInput:
+		utxoType = common.UTXOSpend
 		if !segwit.IsP2WScript(i.ControlProgram) {
 			address = "smart contract"
 			break
@@ -112,18 +168,26 @@ func (c *ClientAdapter) decodeTxInput(input *vaporTypes.TxInput) (*types.UTXO, e
 		if err != nil {
 			return nil, errors.Wrap(err, "ScriptToAddress")
 		}
+		utxoType, vote = common.UTXOVeto, hex.EncodeToString(i.Vote)
+	case *vaporTypes.CrossChainInput:
+		utxoType = common.UTXOCrossChainIn
+	case *vaporTypes.CoinbaseInput:
+		utxoType = common.UTXOCoinbase
 	}
-	tokenParams, ok := common.TokenParams[assetId]
-	if !ok {
-		//ignore invalid token
+
+	utxo := newUTXO(c.tokens, c.unknownAssets, address, amount, assetId)
+	if utxo == nil {
 		return nil, nil
 	}
-	utxo := &types.UTXO{
-		Address:         address,
-		Value:           amount,
-		TokenIdentifier: assetId,
-		TokenCode:       tokenParams.Code,
-		TokenDecimal:    tokenParams.Decimal,
+
+	utxo.Type, utxo.Vote = utxoType, vote
+	utxo.ControlProgram = hex.EncodeToString(input.ControlProgram())
+	if utxoType != common.UTXOCoinbase {
+		spentOutputID, err := input.SpentOutputID()
+		if err != nil {
+			return nil, errors.Wrap(err, "SpentOutputID")
+		}
+		utxo.SpentOutputID = spentOutputID.String()
 	}
 	return utxo, nil
 }
@@ -132,15 +196,17 @@ func (c *ClientAdapter) decodeTxOutput(output *vaporTypes.TxOutput) (*types.UTXO
 	assetId := output.AssetAmount().AssetId.String()
 	amount := output.AssetAmount().Amount
 
-	var address string
+	var address, utxoType, vote string
 	var err error
 
 	switch o := output.TypedOutput.(type) {
 	case *vaporTypes.CrossChainOutput:
-		if assetId == common.ETH || assetId == common.USDT {
+		utxoType = common.UTXOCrossChainOut
+		if isEthereumAsset(assetId) {
 			address = "0x" + hex.EncodeToString(output.ControlProgram())
 		}
 	case *vaporTypes.IntraChainOutput:
+		utxoType = common.UTXOControl
 		address, err = c.scriptToAddress(o.ControlProgram)
 		if err != nil {
 			return nil, errors.Wrap(err, "ScriptToAddress")
@@ -150,21 +216,17 @@ func (c *ClientAdapter) decodeTxOutput(output *vaporTypes.TxOutput) (*types.UTXO
 		if err != nil {
 			return nil, errors.Wrap(err, "ScriptToAddress")
 		}
+		utxoType, vote = common.UTXOVote, hex.EncodeToString(o.Vote)
 	}
-	tokenParams, ok := common.TokenParams[assetId]
-	if !ok {
-		//ignore invalid token
+
+	utxo := newUTXO(c.tokens, c.unknownAssets, address, amount, assetId)
+	if utxo == nil {
 		return nil, nil
 	}
-	utxo := &types.UTXO{
-		Address:         address,
-		Value:           amount,
-		TokenIdentifier: assetId,
-		TokenCode:       tokenParams.Code,
-		TokenDecimal:    tokenParams.Decimal,
-	}
-	return utxo, nil
 
+	utxo.Type, utxo.Vote = utxoType, vote
+	utxo.ControlProgram = hex.EncodeToString(output.ControlProgram())
+	return utxo, nil
 }
 
 func (c *ClientAdapter) scriptToAddress(script []byte) (string, error) {
@@ -1,11 +1,22 @@
 package api
 
 import (
+	"encoding/hex"
+	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/bytom/vapor/blockchain/signers"
+	vaporCommon "github.com/bytom/vapor/common"
 	"github.com/bytom/vapor/consensus"
+	"github.com/bytom/vapor/crypto"
+	"github.com/bytom/vapor/crypto/ed25519/chainkd"
+	"github.com/bytom/vapor/protocol/bc"
+	vaporTypes "github.com/bytom/vapor/protocol/bc/types"
+	"github.com/bytom/vapor/protocol/vm/vmutil"
 
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
 	"vapor-adapter/types"
 )
 
@@ -119,3 +130,361 @@ func TestClientAdapter_PubkeyToAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestClientAdapter_SignTransaction(t *testing.T) {
+	xPrv := chainkd.RootXPrv([]byte("vapor-adapter sign test seed"))
+	otherXPrv := chainkd.RootXPrv([]byte("vapor-adapter other seed"))
+	path := pathForAddress(1, 1, false)
+	derivedXPub := xPrv.XPub().Derive(path)
+	pubHash := crypto.Ripemd160(derivedXPub.PublicKey())
+	controlProgram, err := vmutil.P2WPKHProgram(pubHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txData := vaporTypes.TxData{
+		Version: 1,
+		Inputs:  []*vaporTypes.TxInput{vaporTypes.NewSpendInput(nil, bc.Hash{V0: 1}, *consensus.BTMAssetID, 200, 0, controlProgram)},
+		Outputs: []*vaporTypes.TxOutput{vaporTypes.NewIntraChainOutput(*consensus.BTMAssetID, 100, controlProgram)},
+	}
+	rawTx, err := vaporTypes.NewTx(txData).MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var derivationPath []string
+	for _, p := range path {
+		derivationPath = append(derivationPath, hex.EncodeToString(p))
+	}
+	newTemplate := func() *internal.BuildTransactionResp {
+		return &internal.BuildTransactionResp{
+			RawTransaction: string(rawTx),
+			SigningInstructions: []internal.SigningInstructions{{
+				Position: 0,
+				WitnessComponents: []*internal.WitnessComponent{
+					{Type: "raw_tx_signature", Quorum: 1, Keys: []*internal.KeyID{{Xpub: xPrv.XPub().String(), DerivationPath: derivationPath}}},
+					{Type: "data", Value: hex.EncodeToString(derivedXPub.PublicKey())},
+				},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		xPrvs   []chainkd.XPrv
+		wantErr bool
+	}{
+		{name: "signed", xPrvs: []chainkd.XPrv{otherXPrv, xPrv}, wantErr: false},
+		{name: "missing key", xPrvs: []chainkd.XPrv{otherXPrv}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.SignTransaction(newTemplate(), tt.xPrvs...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SignTransaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			signedTx := &vaporTypes.Tx{}
+			if err := signedTx.UnmarshalText([]byte(got)); err != nil {
+				t.Fatal(err)
+			}
+			arguments := signedTx.Inputs[0].Arguments()
+			if len(arguments) != 2 {
+				t.Fatalf("SignTransaction() arguments = %d, want 2", len(arguments))
+			}
+			sigHash := signedTx.SigHash(0).Byte32()
+			if !derivedXPub.Verify(sigHash[:], arguments[0]) {
+				t.Errorf("SignTransaction() signature does not verify")
+			}
+		})
+	}
+}
+
+func TestClientAdapter_BuildTransaction(t *testing.T) {
+	xPrv := chainkd.RootXPrv([]byte("vapor-adapter sign test seed"))
+	rootXPub := hex.EncodeToString(xPrv.XPub().Bytes())
+	_, _, controlProgram, err := accountProgram([]string{rootXPub}, 1, pathForAddress(1, 2, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unspent := &types.Unspent{
+		SourceID:       "0101010101010101010101010101010101010101010101010101010101010101",
+		SourcePos:      0,
+		ControlProgram: hex.EncodeToString(controlProgram),
+		AssetID:        consensus.BTMAssetID.String(),
+		Amount:         1000,
+		RootXPubs:      []string{rootXPub},
+		Quorum:         1,
+		AccountIndex:   1,
+		AddressIndex:   2,
+	}
+	toAddress := "tp1q3xjrt7ahef583lckefvvhg3djngq0l3rllkkr9"
+	tests := []struct {
+		name        string
+		unspent     *types.Unspent
+		address     string
+		amount      uint64
+		fee         uint64
+		wantOutputs []uint64
+		wantErr     bool
+	}{
+		{name: "with change", unspent: unspent, amount: 600, fee: 100, wantOutputs: []uint64{600, 300}, wantErr: false},
+		{name: "exact", unspent: unspent, amount: 900, fee: 100, wantOutputs: []uint64{900}, wantErr: false},
+		{name: "insufficient", unspent: unspent, amount: 1000, fee: 100, wantErr: true},
+		{name: "wrong account", unspent: &types.Unspent{SourceID: unspent.SourceID, ControlProgram: unspent.ControlProgram, AssetID: unspent.AssetID, Amount: 1000, RootXPubs: unspent.RootXPubs, Quorum: 1, AccountIndex: 1, AddressIndex: 3}, amount: 600, fee: 100, wantErr: true},
+		{name: "zero amount", unspent: unspent, amount: 0, fee: 100, wantErr: true},
+		{name: "bad address", unspent: unspent, address: "tp1qbad", amount: 600, fee: 100, wantErr: true},
+		{name: "wrong network", unspent: unspent, address: "vp1q3xjrt7ahef583lckefvvhg3djngq0l3rllkkr9", amount: 600, fee: 100, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := toAddress
+			if tt.address != "" {
+				address = tt.address
+			}
+			payments := []*types.Payment{{Address: address, AssetID: consensus.BTMAssetID.String(), Amount: tt.amount}}
+			tpl, err := c.BuildTransaction([]*types.Unspent{tt.unspent}, payments, toAddress, tt.fee)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildTransaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			rawTx, err := c.SignTransaction(tpl, xPrv)
+			if err != nil {
+				t.Fatalf("SignTransaction() error = %v", err)
+			}
+			tx := &vaporTypes.Tx{}
+			if err := tx.UnmarshalText([]byte(rawTx)); err != nil {
+				t.Fatal(err)
+			}
+			var gotOutputs []uint64
+			for _, output := range tx.Outputs {
+				gotOutputs = append(gotOutputs, output.AssetAmount().Amount)
+			}
+			if !reflect.DeepEqual(gotOutputs, tt.wantOutputs) {
+				t.Errorf("BuildTransaction() outputs = %v, want %v", gotOutputs, tt.wantOutputs)
+			}
+		})
+	}
+}
+
+func TestClientAdapter_DeserializeVote(t *testing.T) {
+	vote, _ := hex.DecodeString("1c0c2c75073c438b5612005bacdcbde2352277c44a22c5a31aa35899a3369e5fe61bb70eee5c0de48bcefddca59b14162e411b5f11d1966661a25491d48fcdbf")
+	program, err := vmutil.P2WPKHProgram(make([]byte, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := vaporTypes.NewTx(vaporTypes.TxData{
+		Version: 1,
+		Inputs:  []*vaporTypes.TxInput{vaporTypes.NewVetoInput(nil, bc.Hash{V0: 1}, *consensus.BTMAssetID, 300, 0, program, vote)},
+		Outputs: []*vaporTypes.TxOutput{vaporTypes.NewVoteOutput(*consensus.BTMAssetID, 200, program, vote), vaporTypes.NewIntraChainOutput(*consensus.BTMAssetID, 100, program)},
+	})
+	rawTx, err := tx.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.Deserialize(string(rawTx))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantVote := hex.EncodeToString(vote)
+	if got.Inputs[0].Type != common.UTXOVeto || got.Inputs[0].Vote != wantVote {
+		t.Errorf("Deserialize() input = %+v, want veto of %s", got.Inputs[0], wantVote)
+	}
+	if got.Outputs[0].Type != common.UTXOVote || got.Outputs[0].Vote != wantVote {
+		t.Errorf("Deserialize() output = %+v, want vote for %s", got.Outputs[0], wantVote)
+	}
+	if got.Outputs[1].Type != common.UTXOControl || got.Outputs[1].Vote != "" {
+		t.Errorf("Deserialize() output = %+v, want control without vote", got.Outputs[1])
+	}
+
+	spentOutputID, err := tx.Inputs[0].SpentOutputID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Inputs[0].SpentOutputID != spentOutputID.String() || got.Inputs[0].ControlProgram != hex.EncodeToString(program) {
+		t.Errorf("Deserialize() input = %+v, want spent output %s", got.Inputs[0], spentOutputID.String())
+	}
+	for i, output := range got.Outputs {
+		if output.Index != i || output.OutputID != tx.OutputID(i).String() || output.ControlProgram != hex.EncodeToString(program) {
+			t.Errorf("Deserialize() output %d = %+v, want id %s", i, output, tx.OutputID(i).String())
+		}
+	}
+}
+
+func TestClientAdapter_MultisigAddress(t *testing.T) {
+	xPrv := chainkd.RootXPrv([]byte("vapor-adapter sign test seed"))
+	otherXPrv := chainkd.RootXPrv([]byte("vapor-adapter other seed"))
+	xPubs := []chainkd.XPub{xPrv.XPub(), otherXPrv.XPub()}
+	rootXPubs := []string{xPubs[0].String(), xPubs[1].String()}
+
+	signer, err := signers.Create("account", xPubs, 2, 3, signers.BIP0044)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := signers.Path(signer, signers.AccountKeySpace, true, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := vmutil.P2SPMultiSigProgram(chainkd.XPubKeys(chainkd.DeriveXPubs(xPubs, path)), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptHash := crypto.Sha256(script)
+	want, err := vaporCommon.NewAddressWitnessScriptHash(scriptHash, &consensus.TestNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		rootXPubs []string
+		quorum    int
+		want      string
+		wantErr   error
+	}{
+		{name: "node address", rootXPubs: rootXPubs, quorum: 2, want: want.EncodeAddress()},
+		{name: "single key", rootXPubs: []string{"1c0c2c75073c438b5612005bacdcbde2352277c44a22c5a31aa35899a3369e5fe61bb70eee5c0de48bcefddca59b14162e411b5f11d1966661a25491d48fcdbf"}, quorum: 1},
+		{name: "bad quorum", rootXPubs: rootXPubs, quorum: 3, wantErr: common.ErrBadQuorum},
+		{name: "duplicate key", rootXPubs: []string{rootXPubs[0], rootXPubs[0]}, quorum: 1, wantErr: common.ErrDuplicateXPub},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.MultisigAddress(tt.rootXPubs, tt.quorum, 3, 5, true)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MultisigAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != "" && got != tt.want {
+				t.Errorf("MultisigAddress() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	single, err := c.MultisigAddress(tests[1].rootXPubs, 1, 1, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := c.PubkeyToAddress(tests[1].rootXPubs[0]); single != want {
+		t.Errorf("MultisigAddress() single key = %v, want %v", single, want)
+	}
+}
+
+func TestClientAdapter_DeriveAddresses(t *testing.T) {
+	rootXPub := "1c0c2c75073c438b5612005bacdcbde2352277c44a22c5a31aa35899a3369e5fe61bb70eee5c0de48bcefddca59b14162e411b5f11d1966661a25491d48fcdbf"
+	tests := []struct {
+		name         string
+		accountIndex uint64
+		fromIndex    uint64
+		count        int
+		change       bool
+		wantErr      error
+	}{
+		{name: "first", accountIndex: 1, fromIndex: 1, count: 1},
+		{name: "range", accountIndex: 2, fromIndex: 7, count: 5},
+		{name: "change", accountIndex: 1, fromIndex: 1, count: 3, change: true},
+		{name: "no count", accountIndex: 1, fromIndex: 1, count: 0, wantErr: common.ErrBadDerivationIndex},
+		{name: "index overflow", accountIndex: 1, fromIndex: 1<<32 - 2, count: 3, wantErr: common.ErrBadDerivationIndex},
+		{name: "account overflow", accountIndex: 1 << 32, fromIndex: 1, count: 1, wantErr: common.ErrBadDerivationIndex},
+		{name: "count overflow", accountIndex: 1, fromIndex: 1, count: 1<<32 + 2, wantErr: common.ErrBadDerivationIndex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.DeriveAddresses(rootXPub, tt.accountIndex, tt.fromIndex, tt.count, tt.change)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeriveAddresses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var want []string
+			for i := 0; tt.wantErr == nil && i < tt.count; i++ {
+				_, _, program, err := accountProgram([]string{rootXPub}, 1, pathForAddress(tt.accountIndex, tt.fromIndex+uint64(i), tt.change))
+				if err != nil {
+					t.Fatal(err)
+				}
+				address, err := c.scriptToAddress(program)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want = append(want, address)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DeriveAddresses() got = %v, want %v", got, want)
+			}
+		})
+	}
+
+	if got, err := c.DeriveAddress(rootXPub, 1, 1, false); err != nil || got != "tp1q3xjrt7ahef583lckefvvhg3djngq0l3rllkkr9" {
+		t.Errorf("DeriveAddress() = %v, %v", got, err)
+	}
+}
+
+func TestClientAdapter_ValidateAddress(t *testing.T) {
+	pubHash := make([]byte, 20)
+	scriptHash := make([]byte, 32)
+	for i := range scriptHash {
+		scriptHash[i] = byte(i)
+	}
+	copy(pubHash, scriptHash)
+
+	address := func(hash []byte, params *consensus.Params) string {
+		var a vaporCommon.Address
+		var err error
+		if len(hash) == 20 {
+			a, err = vaporCommon.NewAddressWitnessPubKeyHash(hash, params)
+		} else {
+			a, err = vaporCommon.NewAddressWitnessScriptHash(hash, params)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a.EncodeAddress()
+	}
+	p2wpkh := address(pubHash, &consensus.TestNetParams)
+	mainchain := consensus.BytomMainNetParams(&consensus.TestNetParams)
+
+	tests := []struct {
+		name        string
+		assetId     string
+		address     string
+		mainchain   bool
+		wantType    AddressType
+		wantProgram string
+	}{
+		{name: "p2wpkh", address: p2wpkh, wantType: AddressP2WPKH, wantProgram: "0014" + hex.EncodeToString(pubHash)},
+		{name: "p2wsh", address: address(scriptHash, &consensus.TestNetParams), wantType: AddressP2WSH, wantProgram: "0020" + hex.EncodeToString(scriptHash)},
+		{name: "mainnet", address: address(pubHash, &consensus.MainNetParams), wantType: AddressWrongNetwork},
+		{name: "mainchain address", address: address(pubHash, mainchain), wantType: AddressWrongNetwork},
+		{name: "bad checksum", address: p2wpkh[:len(p2wpkh)-1] + "x", wantType: AddressMalformed},
+		{name: "garbage", address: "not an address", wantType: AddressMalformed},
+		{name: "withdraw btm", assetId: common.BTM, address: address(pubHash, mainchain), mainchain: true, wantType: AddressP2WPKH, wantProgram: "0014" + hex.EncodeToString(pubHash)},
+		{name: "withdraw btm to sidechain", assetId: common.BTM, address: p2wpkh, mainchain: true, wantType: AddressWrongNetwork},
+		{name: "withdraw eth", assetId: common.ETH, address: "0x" + hex.EncodeToString(pubHash), mainchain: true, wantType: AddressEthereum, wantProgram: hex.EncodeToString(pubHash)},
+		{name: "withdraw eth short", assetId: common.ETH, address: "0x0102", mainchain: true, wantType: AddressMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *AddressInfo
+			var err error
+			if tt.mainchain {
+				got, err = c.ValidateMainchainAddress(tt.assetId, tt.address)
+			} else {
+				got, err = c.ValidateAddress(tt.address)
+			}
+
+			if valid := tt.wantProgram != ""; valid != (err == nil) || (!valid && !errors.Is(err, common.ErrInvalidAddress)) {
+				t.Errorf("ValidateAddress() error = %v", err)
+			}
+			if got.Type != tt.wantType || got.ControlProgram != tt.wantProgram {
+				t.Errorf("ValidateAddress() got = %+v, want type %v program %v", got, tt.wantType, tt.wantProgram)
+			}
+		})
+	}
+}
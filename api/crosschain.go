@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/bytom/vapor/consensus"
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+)
+
+// ethAddressLen is the byte length of an Ethereum address.
+const ethAddressLen = 20
+
+func (s *ServerAdapter) BuildCrossChainWithdrawal(accountId, mainchainAddress, assetId string, amount uint64) (*internal.BuildTransactionResp, error) {
+	return s.BuildCrossChainWithdrawalWithContext(context.Background(), accountId, mainchainAddress, assetId, amount)
+}
+
+func (s *ServerAdapter) BuildCrossChainWithdrawalWithContext(ctx context.Context, accountId, mainchainAddress, assetId string, amount uint64) (*internal.BuildTransactionResp, error) {
+	return s.BuildCrossChainWithdrawalWithOptionsContext(ctx, accountId, mainchainAddress, assetId, amount, nil)
+}
+
+func (s *ServerAdapter) BuildCrossChainWithdrawalWithOptions(accountId, mainchainAddress, assetId string, amount uint64, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	return s.BuildCrossChainWithdrawalWithOptionsContext(context.Background(), accountId, mainchainAddress, assetId, amount, opts)
+}
+
+// BuildCrossChainWithdrawalWithOptionsContext builds the unsigned transaction
+// moving amount of assetId from accountId back to its mainchain. ETH and USDT
+// go to a 0x Ethereum address, every other asset to a Bytom mainchain address
+// of the adapter's network.
+func (s *ServerAdapter) BuildCrossChainWithdrawalWithOptionsContext(ctx context.Context, accountId, mainchainAddress, assetId string, amount uint64, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	if amount == 0 {
+		return nil, errors.Wrap(common.ErrBadPayment, "withdrawal amount is zero")
+	}
+
+	crossOut := &internal.Actions{Amount: amount, AssetId: assetId, Type: "cross_chain_out"}
+	if isEthereumAsset(assetId) {
+		program, err := ethAddressToProgram(mainchainAddress)
+		if err != nil {
+			return nil, err
+		}
+		crossOut.ControlProgram = hex.EncodeToString(program)
+	} else {
+		if err := validateMainchainAddress(mainchainAddress, s.netParams); err != nil {
+			return nil, err
+		}
+		crossOut.Address = mainchainAddress
+	}
+
+	if err := s.checkBuildOptions(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	actions := []*internal.Actions{
+		{AccountId: accountId, Amount: amount, AssetId: assetId, Type: "spend_account"},
+		crossOut,
+	}
+	return s.buildWithOptions(ctx, accountId, actions, opts)
+}
+
+// isEthereumAsset reports whether assetId was bridged from Ethereum, so that
+// its cross-chain outputs pay to an Ethereum address.
+func isEthereumAsset(assetId string) bool {
+	return assetId == common.ETH || assetId == common.USDT
+}
+
+// ethAddressToProgram decodes a 0x Ethereum address into the control program
+// of a cross-chain output.
+func ethAddressToProgram(address string) ([]byte, error) {
+	if !strings.HasPrefix(address, "0x") && !strings.HasPrefix(address, "0X") {
+		return nil, errors.Wrapf(common.ErrInvalidAddress, "%s has no 0x prefix", address)
+	}
+
+	program, err := hex.DecodeString(address[2:])
+	if err != nil || len(program) != ethAddressLen {
+		return nil, errors.Wrapf(common.ErrInvalidAddress, "%s is not an ethereum address", address)
+	}
+	return program, nil
+}
+
+// validateMainchainAddress checks that address is a P2WPKH or P2WSH address of
+// the Bytom mainchain paired with netParams.
+func validateMainchainAddress(address string, netParams *consensus.Params) error {
+	_, _, err := decodeAddress(address, consensus.BytomMainNetParams(netParams))
+	return err
+}
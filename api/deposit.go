@@ -0,0 +1,300 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"vapor-adapter/types"
+)
+
+// DefaultDepositConfirmations is the confirmation depth a deposit of an asset
+// without a depth of its own needs to be final.
+const DefaultDepositConfirmations = 6
+
+// depositEventBuffer is the capacity of the DepositWatcher event channel.
+const depositEventBuffer = 100
+
+// DepositEventType is the stage of a deposit a DepositEvent reports.
+type DepositEventType int
+
+const (
+	// DepositSeen is a deposit first seen, in the mempool or in a block.
+	DepositSeen DepositEventType = iota
+	// DepositConfirmed is a deposit whose block reached a new depth below the
+	// depth of its asset.
+	DepositConfirmed
+	// DepositFinal is a deposit that reached the depth of its asset. It is no
+	// longer watched.
+	DepositFinal
+	// DepositReverted is a deposit whose block was orphaned or whose
+	// transaction left the mempool without being packed.
+	DepositReverted
+)
+
+// DepositEvent reports a stage of an output paying a watched address.
+type DepositEvent struct {
+	Type          DepositEventType
+	TxHash        string
+	Output        *types.UTXO
+	BlockHeight   uint64
+	Confirmations uint64
+}
+
+type deposit struct {
+	txHash        string
+	output        *types.UTXO
+	blockHeight   uint64
+	confirmations uint64
+}
+
+// DepositWatcher follows the outputs paying watched addresses from the mempool
+// through their block to the confirmation depth of their asset. Deposits still
+// pending when the watcher stops are found again only by a watcher starting
+// from a checkpoint before their block.
+type DepositWatcher struct {
+	server       *ServerAdapter
+	scanner      *BlockScanner
+	defaultDepth uint64
+	depths       map[string]uint64
+	events       chan *DepositEvent
+
+	mu        sync.RWMutex
+	addresses map[string]bool
+
+	deposits map[string]*deposit
+}
+
+type DepositOption func(*DepositWatcher)
+
+// WithConfirmations sets the depth deposits of assetId need to be final.
+func WithConfirmations(assetId string, depth uint64) DepositOption {
+	return func(d *DepositWatcher) {
+		d.depths[assetId] = depth
+	}
+}
+
+// WithDefaultConfirmations sets the depth deposits of the assets without a
+// depth of their own need to be final.
+func WithDefaultConfirmations(depth uint64) DepositOption {
+	return func(d *DepositWatcher) {
+		d.defaultDepth = depth
+	}
+}
+
+// WithDepositScanner replaces the scanner the watcher reads blocks with, for
+// example to give it a checkpoint store.
+func WithDepositScanner(scanner *BlockScanner) DepositOption {
+	return func(d *DepositWatcher) {
+		d.scanner = scanner
+	}
+}
+
+// NewDepositWatcher watches the blocks after checkpoint for deposits to
+// addresses.
+func NewDepositWatcher(server *ServerAdapter, checkpoint Checkpoint, addresses []string, opts ...DepositOption) *DepositWatcher {
+	d := &DepositWatcher{
+		server:       server,
+		scanner:      NewBlockScanner(server, checkpoint),
+		defaultDepth: DefaultDepositConfirmations,
+		depths:       make(map[string]uint64),
+		events:       make(chan *DepositEvent, depositEventBuffer),
+		addresses:    make(map[string]bool),
+		deposits:     make(map[string]*deposit),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.Watch(addresses...)
+	return d
+}
+
+// Events returns the channel the deposit events are sent on. Run closes it
+// when it returns.
+func (d *DepositWatcher) Events() <-chan *DepositEvent {
+	return d.events
+}
+
+// Watch adds addresses to the watched addresses.
+func (d *DepositWatcher) Watch(addresses ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, address := range addresses {
+		d.addresses[address] = true
+	}
+}
+
+// Unwatch stops watching address for new deposits.
+func (d *DepositWatcher) Unwatch(address string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.addresses, address)
+}
+
+// Run polls every interval until ctx is done or a poll fails, then closes the
+// event channel.
+func (d *DepositWatcher) Run(ctx context.Context, interval time.Duration) error {
+	defer close(d.events)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Poll(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll reads the mempool and the new blocks once and sends the resulting
+// events. It must not run concurrently with Run or itself.
+func (d *DepositWatcher) Poll(ctx context.Context) error {
+	// the mempool is read before the blocks, so a transaction missing from it
+	// is either in a scanned block or dropped
+	mempool, err := d.server.GetRawMemPoolWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := d.scanner.Scan(ctx, d.handleBlock); err != nil {
+		return err
+	}
+
+	inMempool := make(map[string]bool)
+	for _, tx := range mempool {
+		for _, output := range tx.Outputs {
+			key := depositKey(tx.TxHash, output)
+			inMempool[key] = true
+			if _, ok := d.deposits[key]; ok || !d.watched(output.Address) {
+				continue
+			}
+
+			dep := &deposit{txHash: tx.TxHash, output: output}
+			d.deposits[key] = dep
+			if err := d.send(ctx, DepositSeen, dep); err != nil {
+				return err
+			}
+		}
+	}
+
+	bestHeight := d.scanner.Checkpoint().Height
+	for key, dep := range d.deposits {
+		if dep.blockHeight == 0 {
+			if !inMempool[key] {
+				delete(d.deposits, key)
+				if err := d.send(ctx, DepositReverted, dep); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := d.confirm(ctx, key, dep, bestHeight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleBlock starts tracking the deposits of a connected block and reverts
+// those of a disconnected one.
+func (d *DepositWatcher) handleBlock(ctx context.Context, event *ScanEvent) error {
+	for _, tx := range event.Block.Txs {
+		for _, output := range tx.Outputs {
+			key := depositKey(tx.TxHash, output)
+			dep, ok := d.deposits[key]
+			if event.Type == BlockDisconnected {
+				if ok && dep.blockHeight == event.Block.Height {
+					delete(d.deposits, key)
+					if err := d.send(ctx, DepositReverted, dep); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			if !ok {
+				if !d.watched(output.Address) {
+					continue
+				}
+
+				dep = &deposit{txHash: tx.TxHash, output: output}
+				d.deposits[key] = dep
+				if err := d.send(ctx, DepositSeen, dep); err != nil {
+					return err
+				}
+			}
+			dep.output, dep.blockHeight = output, event.Block.Height
+		}
+	}
+	return nil
+}
+
+// confirm reports a deposit packed in a block once per new depth, and drops it
+// once final.
+func (d *DepositWatcher) confirm(ctx context.Context, key string, dep *deposit, bestHeight uint64) error {
+	if bestHeight < dep.blockHeight {
+		return nil
+	}
+
+	confirmations := bestHeight - dep.blockHeight + 1
+	if confirmations <= dep.confirmations {
+		return nil
+	}
+
+	dep.confirmations = confirmations
+	if confirmations >= d.depth(dep.output.TokenIdentifier) {
+		delete(d.deposits, key)
+		return d.send(ctx, DepositFinal, dep)
+	}
+	return d.send(ctx, DepositConfirmed, dep)
+}
+
+func (d *DepositWatcher) send(ctx context.Context, eventType DepositEventType, dep *deposit) error {
+	event := &DepositEvent{
+		Type:          eventType,
+		TxHash:        dep.txHash,
+		Output:        dep.output,
+		BlockHeight:   dep.blockHeight,
+		Confirmations: dep.confirmations,
+	}
+
+	select {
+	case d.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *DepositWatcher) watched(address string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.addresses[address]
+}
+
+func (d *DepositWatcher) depth(assetId string) uint64 {
+	if depth, ok := d.depths[assetId]; ok {
+		return depth
+	}
+	return d.defaultDepth
+}
+
+// depositKey identifies output of the transaction txHash.
+func depositKey(txHash string, output *types.UTXO) string {
+	if output.OutputID != "" {
+		return output.OutputID
+	}
+	return txHash + ":" + strconv.Itoa(output.Index)
+}
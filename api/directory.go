@@ -0,0 +1,257 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+)
+
+// AccountRecord is an account of the node and the addresses derived for it.
+type AccountRecord struct {
+	AccountId string   `json:"account_id"`
+	Alias     string   `json:"alias,omitempty"`
+	RootXPubs []string `json:"root_xpubs,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// DirectoryStore persists the records of an AccountDirectory. Save replaces
+// every stored record atomically.
+type DirectoryStore interface {
+	Load() ([]*AccountRecord, error)
+	Save(records []*AccountRecord) error
+}
+
+// MemoryDirectoryStore keeps the records in memory.
+type MemoryDirectoryStore struct {
+	mu      sync.Mutex
+	records []*AccountRecord
+}
+
+func NewMemoryDirectoryStore() *MemoryDirectoryStore {
+	return &MemoryDirectoryStore{}
+}
+
+func (m *MemoryDirectoryStore) Load() ([]*AccountRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return copyRecords(m.records), nil
+}
+
+func (m *MemoryDirectoryStore) Save(records []*AccountRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records = copyRecords(records)
+	return nil
+}
+
+// FileDirectoryStore keeps the records as JSON in a file, replaced through a
+// temporary file on every Save.
+type FileDirectoryStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileDirectoryStore(path string) *FileDirectoryStore {
+	return &FileDirectoryStore{path: path}
+}
+
+func (f *FileDirectoryStore) Load() ([]*AccountRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "read directory file")
+	}
+
+	var records []*AccountRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, errors.Wrapf(err, "decode directory file %s", f.path)
+	}
+	return records, nil
+}
+
+func (f *FileDirectoryStore) Save(records []*AccountRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "encode directory")
+	}
+
+	return writeFileAtomic(f.path, data)
+}
+
+// AccountDirectory maps the accounts of the node to their addresses and back.
+// Every change is saved to its store before it is visible.
+type AccountDirectory struct {
+	mu        sync.RWMutex
+	store     DirectoryStore
+	accounts  map[string]*AccountRecord
+	addresses map[string]string
+	xpubs     map[string]string
+}
+
+// NewAccountDirectory loads the records of store.
+func NewAccountDirectory(store DirectoryStore) (*AccountDirectory, error) {
+	records, err := store.Load()
+	if err != nil {
+		return nil, errors.Wrap(err, "load account directory")
+	}
+
+	d := &AccountDirectory{store: store}
+	d.index(records)
+	return d, nil
+}
+
+// AddAccount records the account accountId, or updates its alias and root
+// xpubs when it is already recorded.
+func (d *AccountDirectory) AddAccount(accountId, alias string, rootXPubs []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	records := d.records()
+	record := findRecord(records, accountId)
+	if record == nil {
+		record = &AccountRecord{AccountId: accountId}
+		records = append(records, record)
+	}
+	record.Alias = alias
+	record.RootXPubs = append([]string(nil), rootXPubs...)
+
+	return d.save(records)
+}
+
+// AddAddress records address as an address of accountId, recording accountId
+// without alias when it is not recorded yet.
+func (d *AccountDirectory) AddAddress(accountId, address string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if owner, ok := d.addresses[address]; ok {
+		if owner == accountId {
+			return nil
+		}
+		return errors.Wrapf(common.ErrAddressTaken, "%s belongs to account %s", address, owner)
+	}
+
+	records := d.records()
+	record := findRecord(records, accountId)
+	if record == nil {
+		record = &AccountRecord{AccountId: accountId}
+		records = append(records, record)
+	}
+	record.Addresses = append(record.Addresses, address)
+
+	return d.save(records)
+}
+
+// Account returns the record of accountId.
+func (d *AccountDirectory) Account(accountId string) (*AccountRecord, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	record, ok := d.accounts[accountId]
+	if !ok {
+		return nil, false
+	}
+	return copyRecords([]*AccountRecord{record})[0], true
+}
+
+// AccountForAddress returns the account address belongs to.
+func (d *AccountDirectory) AccountForAddress(address string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	accountId, ok := d.addresses[address]
+	return accountId, ok
+}
+
+// AccountForXPub returns the account created on the root xpub rootXPub.
+func (d *AccountDirectory) AccountForXPub(rootXPub string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	accountId, ok := d.xpubs[rootXPub]
+	return accountId, ok
+}
+
+// Addresses returns the addresses recorded for accountId.
+func (d *AccountDirectory) Addresses(accountId string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if record, ok := d.accounts[accountId]; ok {
+		return append([]string(nil), record.Addresses...)
+	}
+	return nil
+}
+
+// save stores records and indexes them once stored.
+func (d *AccountDirectory) save(records []*AccountRecord) error {
+	if err := d.store.Save(records); err != nil {
+		return errors.Wrap(err, "save account directory")
+	}
+
+	d.index(records)
+	return nil
+}
+
+func (d *AccountDirectory) index(records []*AccountRecord) {
+	d.accounts = make(map[string]*AccountRecord)
+	d.addresses = make(map[string]string)
+	d.xpubs = make(map[string]string)
+	for _, record := range records {
+		d.accounts[record.AccountId] = record
+		for _, address := range record.Addresses {
+			d.addresses[address] = record.AccountId
+		}
+		if len(record.RootXPubs) == 1 {
+			d.xpubs[record.RootXPubs[0]] = record.AccountId
+		}
+	}
+}
+
+// records returns a copy of the records, sorted by account id, to change and
+// save.
+func (d *AccountDirectory) records() []*AccountRecord {
+	var records []*AccountRecord
+	for _, record := range d.accounts {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].AccountId < records[j].AccountId })
+	return copyRecords(records)
+}
+
+func findRecord(records []*AccountRecord, accountId string) *AccountRecord {
+	for _, record := range records {
+		if record.AccountId == accountId {
+			return record
+		}
+	}
+	return nil
+}
+
+func copyRecords(records []*AccountRecord) []*AccountRecord {
+	var copied []*AccountRecord
+	for _, record := range records {
+		copied = append(copied, &AccountRecord{
+			AccountId: record.AccountId,
+			Alias:     record.Alias,
+			RootXPubs: append([]string(nil), record.RootXPubs...),
+			Addresses: append([]string(nil), record.Addresses...),
+		})
+	}
+	return copied
+}
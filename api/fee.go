@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+)
+
+// draftTTL is the reservation time of the UTXOs of a transaction built only to
+// estimate its fee by EstimateFee, in milliseconds.
+const draftTTL = 1
+
+// FeeMode selects how a build picks its transaction fee.
+type FeeMode int
+
+const (
+	// FeeNone adds no fee. The node adds none either, so the transaction
+	// only pays a fee when the built actions spend more BTM than they send.
+	FeeNone FeeMode = iota
+	// FeeFixed pays FeePolicy.Amount.
+	FeeFixed
+	// FeeEstimated pays the gas estimated by the node for the transaction.
+	FeeEstimated
+	// FeeEstimatedWithMargin pays the estimate plus FeePolicy.MarginPercent.
+	FeeEstimatedWithMargin
+)
+
+// FeePolicy is the BTM fee, in neu, paid by a built transaction.
+type FeePolicy struct {
+	Mode          FeeMode
+	Amount        uint64
+	MarginPercent uint64
+	// MaxFee, when not zero, fails the build if the fee would exceed it.
+	MaxFee uint64
+}
+
+func (s *ServerAdapter) EstimateFee(accountId, toAddress, tokenIdentifier string, amount uint64) (uint64, error) {
+	return s.EstimateFeeWithContext(context.Background(), accountId, toAddress, tokenIdentifier, amount)
+}
+
+// EstimateFeeWithContext estimates the fee of sending amount of tokenIdentifier
+// from accountId to toAddress. The UTXOs of the draft transaction stay
+// reserved for up to a second, so a build right after it may find them taken;
+// BuildTransactionWithFee estimates without that delay.
+func (s *ServerAdapter) EstimateFeeWithContext(ctx context.Context, accountId, toAddress, tokenIdentifier string, amount uint64) (uint64, error) {
+	return s.estimateFee(ctx, &internal.BuildTransactionReq{Actions: transferActions(accountId, toAddress, tokenIdentifier, amount)})
+}
+
+func (s *ServerAdapter) EstimateTransactionFee(tpl *internal.BuildTransactionResp) (uint64, error) {
+	return s.EstimateTransactionFeeWithContext(context.Background(), tpl)
+}
+
+// EstimateTransactionFeeWithContext asks the node for the gas tpl consumes and
+// returns it as a fee in neu.
+func (s *ServerAdapter) EstimateTransactionFeeWithContext(ctx context.Context, tpl *internal.BuildTransactionResp) (uint64, error) {
+	req := &internal.EstimateTxGasReq{TxTemplate: tpl}
+	resp := &internal.EstimateTxGasResp{}
+	if err := s.call(ctx, "/estimate-transaction-gas", req, resp, true); err != nil {
+		return 0, errors.Wrapf(err, "request estimate transaction gas")
+	}
+
+	if resp.TotalNeu < 0 {
+		return 0, nil
+	}
+	return uint64(resp.TotalNeu), nil
+}
+
+func (s *ServerAdapter) BuildTransactionWithFee(accountId, toAddress, tokenIdentifier string, amount uint64, fee *FeePolicy) (*internal.BuildTransactionResp, error) {
+	return s.BuildTransactionWithFeeContext(context.Background(), accountId, toAddress, tokenIdentifier, amount, fee)
+}
+
+// BuildTransactionWithFeeContext is BuildTransactionWithContext paying the fee
+// chosen by the policy with an extra BTM spend from accountId. The fee of the
+// built transaction is reported in its Fee field.
+func (s *ServerAdapter) BuildTransactionWithFeeContext(ctx context.Context, accountId, toAddress, tokenIdentifier string, amount uint64, fee *FeePolicy) (*internal.BuildTransactionResp, error) {
+	return s.BuildTransactionWithOptionsContext(ctx, accountId, toAddress, tokenIdentifier, amount, &BuildOptions{Fee: fee})
+}
+
+func (p *FeePolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+
+	switch p.Mode {
+	case FeeNone, FeeEstimated, FeeEstimatedWithMargin:
+	case FeeFixed:
+		if p.MaxFee > 0 && p.Amount > p.MaxFee {
+			return errors.Wrapf(common.ErrBadBuildOptions, "fixed fee %d above max fee %d", p.Amount, p.MaxFee)
+		}
+	default:
+		return errors.Wrapf(common.ErrBadBuildOptions, "unknown fee mode %d", p.Mode)
+	}
+	return nil
+}
+
+// buildWithFee builds req paying the fee of policy with a BTM spend from
+// accountId. An estimated fee is estimated on the transaction of req, built
+// first, and then paid by a second build on top of it, so the estimate covers
+// the inputs really spent and no UTXO is reserved twice. The UTXOs of that
+// first transaction stay reserved for the TTL of req when the fee turns out
+// to exceed the MaxFee of the policy.
+func (s *ServerAdapter) buildWithFee(ctx context.Context, accountId string, req *internal.BuildTransactionReq, policy *FeePolicy) (*internal.BuildTransactionResp, error) {
+	if policy == nil || policy.Mode == FeeNone {
+		return s.buildTransaction(ctx, req)
+	}
+
+	if policy.Mode == FeeFixed {
+		if policy.Amount > 0 {
+			req.Actions = append(req.Actions, feeAction(accountId, policy.Amount))
+		}
+		return s.buildTransaction(ctx, req)
+	}
+
+	tpl, err := s.buildTransaction(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	estimated, err := s.EstimateTransactionFeeWithContext(ctx, tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	fee := estimated
+	if policy.Mode == FeeEstimatedWithMargin {
+		fee += estimated * policy.MarginPercent / 100
+	}
+
+	if policy.MaxFee > 0 && fee > policy.MaxFee {
+		return nil, errors.Wrapf(common.ErrFeeTooHigh, "fee %d, max %d", fee, policy.MaxFee)
+	}
+
+	if fee == 0 {
+		return tpl, nil
+	}
+
+	feeReq := &internal.BuildTransactionReq{
+		BaseTransaction: tpl.RawTransaction,
+		Actions:         []*internal.Actions{feeAction(accountId, fee)},
+		TTL:             req.TTL,
+		TimeRange:       req.TimeRange,
+	}
+	feeTpl, err := s.buildTransaction(ctx, feeReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "build fee")
+	}
+
+	// the node only returns the signing instructions of the inputs it added
+	feeTpl.SigningInstructions = append(tpl.SigningInstructions, feeTpl.SigningInstructions...)
+	return feeTpl, nil
+}
+
+func feeAction(accountId string, fee uint64) *internal.Actions {
+	return &internal.Actions{AccountId: accountId, Amount: fee, AssetId: common.BTM, Type: "spend_account"}
+}
+
+// estimateFee builds a short-lived draft of req and estimates its fee. The
+// node only sweeps expired reservations every second, so the UTXOs of the
+// draft stay reserved for up to a second.
+func (s *ServerAdapter) estimateFee(ctx context.Context, req *internal.BuildTransactionReq) (uint64, error) {
+	draftReq := *req
+	draftReq.TTL = draftTTL
+	draft, err := s.buildTransaction(ctx, &draftReq)
+	if err != nil {
+		return 0, errors.Wrap(err, "build draft transaction")
+	}
+
+	return s.EstimateTransactionFeeWithContext(ctx, draft)
+}
+
+// transferActions spends amount of tokenIdentifier from accountId to toAddress.
+func transferActions(accountId, toAddress, tokenIdentifier string, amount uint64) []*internal.Actions {
+	return []*internal.Actions{
+		{AccountId: accountId, Amount: amount, AssetId: tokenIdentifier, Type: "spend_account"},
+		{Amount: amount, AssetId: tokenIdentifier, Type: "control_address", Address: toAddress},
+	}
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+)
+
+// NodeStatus is the last known state of one vapord endpoint.
+type NodeStatus struct {
+	Addr      string
+	Healthy   bool
+	Syncing   bool
+	Height    uint64
+	Latency   time.Duration
+	CheckedAt time.Time
+	LastErr   error
+	// FailedAt is when a call last found the node down, zero once it served
+	// a call or answered a health check again.
+	FailedAt time.Time
+}
+
+// CallInfo describes a single request made by a ServerAdapter, reported to
+// the observer installed with WithCallObserver.
+type CallInfo struct {
+	Node     string
+	Path     string
+	Duration time.Duration
+	Err      error
+}
+
+// chainReads are the paths every synced node answers alike. They are routed
+// to the healthiest node and fail over, while every other path is pinned to
+// the wallet node, which holds the accounts, UTXO reservations and signing
+// keys. The node answers get-transaction from its wallet transaction index, so
+// fallback nodes need the wallet enabled unless the path is pinned with
+// WithPinnedPaths.
+var chainReads = map[string]bool{
+	"/get-block":                     true,
+	"/get-block-count":               true,
+	"/get-transaction":               true,
+	"/get-unconfirmed-transaction":   true,
+	"/list-unconfirmed-transactions": true,
+}
+
+// WithNodes adds fallback vapord endpoints. Chain reads are routed to the
+// healthiest node and fail over to the others on connection or HTTP errors;
+// wallet calls stay on the wallet node. A node failing a call is routed after
+// the healthy ones, and tried first again once the node retry interval has
+// passed, so it recovers without StartHealthCheck.
+func WithNodes(nodeAddrs ...string) ServerOption {
+	return func(s *ServerAdapter) {
+		for _, addr := range nodeAddrs {
+			s.nodes.add(addr)
+		}
+	}
+}
+
+// WithNodeRetryInterval sets how long a node that failed a call is routed after
+// the healthy ones, common.DefaultNodeRetryInterval by default.
+func WithNodeRetryInterval(interval time.Duration) ServerOption {
+	return func(s *ServerAdapter) {
+		s.nodes.retryInterval = interval
+	}
+}
+
+// WithWalletNode pins the wallet calls to nodeAddr instead of the node given
+// to NewServerAdapter, adding it to the nodes when needed.
+func WithWalletNode(nodeAddr string) ServerOption {
+	return func(s *ServerAdapter) {
+		s.nodes.add(nodeAddr)
+		s.walletNode = nodeAddr
+	}
+}
+
+// WithPinnedPaths pins chain read paths such as "/get-transaction" to the
+// wallet node as well.
+func WithPinnedPaths(paths ...string) ServerOption {
+	return func(s *ServerAdapter) {
+		for _, path := range paths {
+			s.pinned[path] = true
+		}
+	}
+}
+
+// WithCallObserver registers fn to be told which node served every call.
+func WithCallObserver(fn func(CallInfo)) ServerOption {
+	return func(s *ServerAdapter) {
+		s.observer = fn
+	}
+}
+
+type nodePool struct {
+	mu            sync.RWMutex
+	nodes         []*NodeStatus
+	retryInterval time.Duration
+}
+
+func newNodePool(addr string) *nodePool {
+	p := &nodePool{retryInterval: common.DefaultNodeRetryInterval}
+	p.add(addr)
+	return p
+}
+
+func (p *nodePool) add(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, n := range p.nodes {
+		if n.Addr == addr {
+			return
+		}
+	}
+	p.nodes = append(p.nodes, &NodeStatus{Addr: addr, Healthy: true})
+}
+
+// route returns the node addresses ordered from the most to the least
+// preferred: healthy before unhealthy, then highest block, then lowest latency.
+// A node that failed a call counts as healthy again after the retry interval.
+func (p *nodePool) route() []string {
+	statuses := p.statuses()
+	now := time.Now()
+	for i := range statuses {
+		if !statuses[i].FailedAt.IsZero() && now.Sub(statuses[i].FailedAt) >= p.retryInterval {
+			statuses[i].Healthy = true
+		}
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		if a.Healthy != b.Healthy {
+			return a.Healthy
+		}
+		if a.Height != b.Height {
+			return a.Height > b.Height
+		}
+		return a.Latency < b.Latency
+	})
+
+	addrs := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		addrs = append(addrs, status.Addr)
+	}
+	return addrs
+}
+
+func (p *nodePool) statuses() []NodeStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	statuses := make([]NodeStatus, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		statuses = append(statuses, *n)
+	}
+	return statuses
+}
+
+func (p *nodePool) update(addr string, fn func(n *NodeStatus)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, n := range p.nodes {
+		if n.Addr == addr {
+			fn(n)
+			return
+		}
+	}
+}
+
+// NodeStatuses returns the last known state of every configured node.
+func (s *ServerAdapter) NodeStatuses() []NodeStatus {
+	return s.nodes.statuses()
+}
+
+// CheckNodes probes every node with net-info once and updates their health.
+// A node is healthy when it answers and is not syncing.
+func (s *ServerAdapter) CheckNodes(ctx context.Context) []NodeStatus {
+	var wg sync.WaitGroup
+	for _, status := range s.nodes.statuses() {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+
+			start := time.Now()
+			resp := &internal.NetInfoResp{}
+			err := s.RequestVaporWithContext(ctx, addr+"/net-info", nil, resp)
+			s.nodes.update(addr, func(n *NodeStatus) {
+				n.CheckedAt = time.Now()
+				n.FailedAt = time.Time{}
+				n.LastErr = err
+				n.Healthy = err == nil && !resp.Syncing
+				if err == nil {
+					n.Syncing = resp.Syncing
+					n.Height = resp.CurrentBlock
+					n.Latency = time.Since(start)
+				}
+			})
+		}(status.Addr)
+	}
+	wg.Wait()
+	return s.nodes.statuses()
+}
+
+// StartHealthCheck runs CheckNodes every interval until ctx is done.
+func (s *ServerAdapter) StartHealthCheck(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.CheckNodes(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// call sends req to the wallet node, or to the preferred node for a chain
+// read. Idempotent calls are retried per the retry policy, and chain reads
+// then fail over to the remaining nodes.
+func (s *ServerAdapter) call(ctx context.Context, path string, req interface{}, resp interface{}, idempotent bool) error {
+	addrs := []string{s.walletNode}
+	if chainReads[path] && !s.pinned[path] {
+		addrs = s.nodes.route()
+		if !idempotent {
+			addrs = addrs[:1]
+		}
+	}
+
+	var err error
+	for _, addr := range addrs {
+		start := time.Now()
+		if idempotent {
+			err = s.requestVaporIdempotent(ctx, addr+path, req, resp)
+		} else {
+			err = s.RequestVaporWithContext(ctx, addr+path, req, resp)
+		}
+
+		if s.observer != nil {
+			s.observer(CallInfo{Node: addr, Path: path, Duration: time.Since(start), Err: err})
+		}
+
+		if err == nil || ctx.Err() != nil || !isNodeFailure(err) {
+			s.nodes.update(addr, func(n *NodeStatus) {
+				// the node recovered from the failure of an earlier call
+				if !n.FailedAt.IsZero() {
+					n.Healthy, n.LastErr, n.FailedAt = true, nil, time.Time{}
+				}
+			})
+			return err
+		}
+
+		s.nodes.update(addr, func(n *NodeStatus) {
+			n.Healthy = false
+			n.LastErr = err
+			n.FailedAt = time.Now()
+		})
+	}
+	return err
+}
+
+// isNodeFailure reports whether err means the node itself could not serve the
+// request, as opposed to the node rejecting it.
+func isNodeFailure(err error) bool {
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	return errors.Is(err, common.ErrNodeUnavailable)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"vapor-adapter/common"
+)
+
+// ClientOption customizes a ClientAdapter created by NewClientAdapter.
+type ClientOption func(*ClientAdapter)
+
+// ServerOption customizes a ServerAdapter created by NewServerAdapter.
+type ServerOption func(*ServerAdapter)
+
+// WithHTTPClient makes the adapter send every node request through client, so
+// its transport, proxy and connection pool settings are shared by all calls.
+func WithHTTPClient(client *http.Client) ServerOption {
+	return func(s *ServerAdapter) {
+		if client != nil {
+			s.httpClient = client
+		}
+	}
+}
+
+// WithTransport replaces the round tripper of the adapter's HTTP client, e.g.
+// an *http.Transport with a custom TLS config or keep-alive pool size.
+func WithTransport(transport http.RoundTripper) ServerOption {
+	return func(s *ServerAdapter) {
+		client := *s.httpClient
+		client.Transport = transport
+		s.httpClient = &client
+	}
+}
+
+// WithHeader adds a header sent with every node request.
+func WithHeader(key, value string) ServerOption {
+	return func(s *ServerAdapter) {
+		s.header[key] = value
+	}
+}
+
+// WithTimeout sets the default per-request timeout, see SetTimeout.
+func WithTimeout(timeout time.Duration) ServerOption {
+	return func(s *ServerAdapter) {
+		s.timeout = timeout
+	}
+}
+
+// WithTokenRegistry makes the adapter resolve assets through tokens instead of
+// a private copy of common.TokenParams.
+func WithTokenRegistry(tokens *common.TokenRegistry) ServerOption {
+	return func(s *ServerAdapter) {
+		if tokens != nil {
+			s.tokens = tokens
+		}
+	}
+}
+
+// WithClientTokenRegistry makes the client adapter resolve assets through
+// tokens instead of a private copy of common.TokenParams.
+func WithClientTokenRegistry(tokens *common.TokenRegistry) ClientOption {
+	return func(c *ClientAdapter) {
+		if tokens != nil {
+			c.tokens = tokens
+		}
+	}
+}
+
+// WithUnknownAssetMode selects how assets missing from the token registry are
+// reported, see common.UnknownAssetMode.
+func WithUnknownAssetMode(mode common.UnknownAssetMode) ServerOption {
+	return func(s *ServerAdapter) {
+		s.unknownAssets = mode
+	}
+}
+
+// WithClientUnknownAssetMode selects how assets missing from the token registry
+// are reported. UnknownAssetLookup behaves as UnknownAssetKeep offline.
+func WithClientUnknownAssetMode(mode common.UnknownAssetMode) ClientOption {
+	return func(c *ClientAdapter) {
+		c.unknownAssets = mode
+	}
+}
+
+// WithAssetRetryInterval sets how long UnknownAssetLookup leaves an asset the
+// node could not resolve unknown before asking for it again. A zero interval
+// asks again every time the asset is seen.
+func WithAssetRetryInterval(interval time.Duration) ServerOption {
+	return func(s *ServerAdapter) {
+		s.assetRetry = interval
+	}
+}
+
+// WithAssetLookupObserver registers fn to be told about every asset
+// UnknownAssetLookup failed to resolve, and why.
+func WithAssetLookupObserver(fn func(assetId string, err error)) ServerOption {
+	return func(s *ServerAdapter) {
+		s.assetObserver = fn
+	}
+}
+
+// WithBatchLimits bounds the inputs and outputs of every transaction built by
+// BuildBatchTransaction. A limit of zero or less is no limit.
+func WithBatchLimits(maxInputs, maxOutputs int) ServerOption {
+	return func(s *ServerAdapter) {
+		s.maxTxInputs = maxInputs
+		s.maxTxOutputs = maxOutputs
+	}
+}
+
+// WithAccountDirectory makes the adapter record the accounts and addresses it
+// creates in dir, and accept the recorded addresses where it takes accountIds
+// to query balances and transactions.
+func WithAccountDirectory(dir *AccountDirectory) ServerOption {
+	return func(s *ServerAdapter) {
+		s.directory = dir
+	}
+}
+
+// WithXPubAccountReuse makes CreateAccount return the existing account of a
+// single key whatever alias it is asked for, so a key never gets a second
+// account. The account is looked up in the account directory, or else among
+// every account of the node.
+func WithXPubAccountReuse() ServerOption {
+	return func(s *ServerAdapter) {
+		s.reuseXPubAccounts = true
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+)
+
+// RetryPolicy controls how idempotent read calls of a ServerAdapter are
+// retried after a transient failure. Calls that build, sign or create
+// anything on the node are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt, doubled after
+	// each further failure and capped by MaxBackoff unless it is zero.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter randomly shortens each delay by up to this fraction (0 to 1).
+	Jitter float64
+	// RetryableStatus lists the HTTP status codes worth retrying.
+	RetryableStatus []int
+	// Retryable, when set, overrides the default classification of errors.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy retries timeouts, refused or reset connections and gateway
+// errors up to three times in total.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:     3,
+		InitialBackoff:  200 * time.Millisecond,
+		MaxBackoff:      2 * time.Second,
+		Jitter:          0.2,
+		RetryableStatus: []int{http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+// WithRetryPolicy enables retries of idempotent read calls.
+func WithRetryPolicy(policy *RetryPolicy) ServerOption {
+	return func(s *ServerAdapter) {
+		s.retryPolicy = policy
+	}
+}
+
+func (p *RetryPolicy) isRetryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	switch e := err.(type) {
+	case *common.NodeError:
+		for _, code := range p.RetryableStatus {
+			if e.HTTPStatus == code {
+				return true
+			}
+		}
+		return false
+	case net.Error:
+		// a bad URL or certificate fails the same way every time
+		return e.Timeout() || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+	}
+	return false
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		if (p.MaxBackoff > 0 && delay >= p.MaxBackoff) || delay > math.MaxInt64/2 {
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// requestVaporIdempotent is RequestVaporWithContext for read-only endpoints,
+// retried according to the adapter's retry policy.
+func (s *ServerAdapter) requestVaporIdempotent(ctx context.Context, url string, req interface{}, resp interface{}) error {
+	policy := s.retryPolicy
+	if policy == nil || policy.MaxAttempts <= 1 {
+		return s.RequestVaporWithContext(ctx, url, req, resp)
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = s.RequestVaporWithContext(ctx, url, req, resp); err == nil {
+			return nil
+		}
+
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || !policy.isRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+	"vapor-adapter/types"
+)
+
+// DefaultMaxReorgDepth is the deepest chain reorganization a BlockScanner
+// rolls back before giving up.
+const DefaultMaxReorgDepth = 100
+
+// Checkpoint is the last block a BlockScanner processed. The zero Checkpoint
+// scans from block 1.
+type Checkpoint struct {
+	Height uint64 `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// ScanEventType tells whether a ScanEvent adds a block to the best chain or
+// rolls back an orphaned one.
+type ScanEventType int
+
+const (
+	BlockConnected ScanEventType = iota
+	BlockDisconnected
+)
+
+// ScanEvent is a block connected to or disconnected from the best chain. The
+// transactions of a disconnected block are orphaned and have no confirmations.
+type ScanEvent struct {
+	Type  ScanEventType
+	Block *types.Block
+}
+
+// ScanHandler processes a ScanEvent. An error stops the scan before the
+// checkpoint moves past the event, so the event is delivered again.
+type ScanHandler func(ctx context.Context, event *ScanEvent) error
+
+// BlockScanner walks the blocks of the best chain from a checkpoint. When the
+// chain reorganizes it disconnects the orphaned blocks, newest first, before
+// connecting the blocks of the new best chain.
+type BlockScanner struct {
+	server        *ServerAdapter
+	checkpoint    Checkpoint
+	maxReorgDepth int
+	store         CheckpointStore
+	loaded        bool
+}
+
+type ScannerOption func(*BlockScanner)
+
+// WithMaxReorgDepth sets the deepest reorganization the scanner rolls back.
+func WithMaxReorgDepth(depth int) ScannerOption {
+	return func(b *BlockScanner) {
+		b.maxReorgDepth = depth
+	}
+}
+
+// WithCheckpointStore makes the scanner resume from the checkpoint stored in
+// store, if any, and save its checkpoint there after every handled event.
+func WithCheckpointStore(store CheckpointStore) ScannerOption {
+	return func(b *BlockScanner) {
+		b.store = store
+	}
+}
+
+func NewBlockScanner(server *ServerAdapter, checkpoint Checkpoint, opts ...ScannerOption) *BlockScanner {
+	b := &BlockScanner{server: server, checkpoint: checkpoint, maxReorgDepth: DefaultMaxReorgDepth}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Checkpoint returns the last block the scanner processed. With a checkpoint
+// store it is only the stored one after the first Scan.
+func (b *BlockScanner) Checkpoint() Checkpoint {
+	return b.checkpoint
+}
+
+// Scan hands every block up to the current best height to handler.
+func (b *BlockScanner) Scan(ctx context.Context, handler ScanHandler) error {
+	if err := b.load(); err != nil {
+		return err
+	}
+
+	for {
+		bestHeight, err := b.server.GetBlockCountWithContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		if b.checkpoint.Height >= bestHeight {
+			// no next block links to the checkpoint, so check it is still on the
+			// best chain itself
+			if b.checkpoint.Hash != "" {
+				if err := b.rollback(ctx, bestHeight, handler); err != nil {
+					return err
+				}
+			}
+
+			if b.checkpoint.Height >= bestHeight {
+				return nil
+			}
+			continue
+		}
+
+		block, err := b.server.getBlock(ctx, &internal.GetBlockReq{BlockHeight: b.checkpoint.Height + 1}, bestHeight)
+		if err != nil {
+			return err
+		}
+
+		if b.checkpoint.Hash != "" && block.PreviousHash != b.checkpoint.Hash {
+			checkpoint := b.checkpoint
+			if err := b.rollback(ctx, bestHeight, handler); err != nil {
+				return err
+			}
+
+			if b.checkpoint == checkpoint {
+				return errors.Errorf("block %s does not link to best chain block %s", block.Hash, checkpoint.Hash)
+			}
+			continue
+		}
+
+		if err := handler(ctx, &ScanEvent{Type: BlockConnected, Block: block}); err != nil {
+			return err
+		}
+
+		if err := b.commit(Checkpoint{Height: block.Height, Hash: block.Hash}); err != nil {
+			return err
+		}
+	}
+}
+
+// Run scans every interval until ctx is done or a scan fails.
+func (b *BlockScanner) Run(ctx context.Context, interval time.Duration, handler ScanHandler) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Scan(ctx, handler); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// rollback disconnects the checkpoint blocks until the checkpoint is back on
+// the best chain of bestHeight.
+func (b *BlockScanner) rollback(ctx context.Context, bestHeight uint64, handler ScanHandler) error {
+	for depth := 0; ; depth++ {
+		if b.checkpoint.Height <= bestHeight {
+			block, err := b.server.getBlock(ctx, &internal.GetBlockReq{BlockHeight: b.checkpoint.Height}, bestHeight)
+			if err != nil {
+				return err
+			}
+
+			if block.Hash == b.checkpoint.Hash {
+				return nil
+			}
+		}
+
+		if depth >= b.maxReorgDepth {
+			return errors.Wrapf(common.ErrReorgTooDeep, "rolled back %d blocks to height %d", depth, b.checkpoint.Height)
+		}
+
+		orphan, err := b.server.getBlock(ctx, &internal.GetBlockReq{BlockHash: b.checkpoint.Hash}, bestHeight)
+		if err != nil {
+			return err
+		}
+
+		for _, tx := range orphan.Txs {
+			tx.Confirmations = 0
+		}
+
+		if err := handler(ctx, &ScanEvent{Type: BlockDisconnected, Block: orphan}); err != nil {
+			return err
+		}
+
+		if err := b.commit(Checkpoint{Height: orphan.Height - 1, Hash: orphan.PreviousHash}); err != nil {
+			return err
+		}
+	}
+}
+
+// load replaces the checkpoint with the stored one the first time it is
+// called.
+func (b *BlockScanner) load() error {
+	if b.store == nil || b.loaded {
+		return nil
+	}
+
+	checkpoint, err := b.store.Load()
+	if err != nil {
+		return errors.Wrap(err, "load checkpoint")
+	}
+
+	if checkpoint != nil {
+		b.checkpoint = *checkpoint
+	}
+	b.loaded = true
+	return nil
+}
+
+// commit moves the checkpoint once it is saved to the store.
+func (b *BlockScanner) commit(checkpoint Checkpoint) error {
+	if b.store != nil {
+		if err := b.store.Save(checkpoint); err != nil {
+			return errors.Wrap(err, "save checkpoint")
+		}
+	}
+
+	b.checkpoint = checkpoint
+	return nil
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+
+	"github.com/bytom/vapor/crypto/ed25519/chainkd"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+)
+
+// Signer turns a built transaction into a signed raw transaction hex.
+type Signer func(ctx context.Context, tpl *internal.BuildTransactionResp) (string, error)
+
+// NodeSigner signs with the keys held by the node, unlocked by password.
+func NodeSigner(s *ServerAdapter, password string) Signer {
+	return func(ctx context.Context, tpl *internal.BuildTransactionResp) (string, error) {
+		signed, complete, err := s.SignTransactionWithContext(ctx, tpl, password)
+		if err != nil {
+			return "", err
+		}
+
+		if !complete {
+			return "", common.ErrNotEnoughSigs
+		}
+		return signed.RawTransaction, nil
+	}
+}
+
+// ClientSigner signs offline with the given root private keys.
+func ClientSigner(c *ClientAdapter, xPrvs ...chainkd.XPrv) Signer {
+	return func(ctx context.Context, tpl *internal.BuildTransactionResp) (string, error) {
+		return c.SignTransaction(tpl, xPrvs...)
+	}
+}
+
+func (s *ServerAdapter) Send(accountId, toAddress, tokenIdentifier string, amount uint64, signer Signer) (string, error) {
+	return s.SendWithContext(context.Background(), accountId, toAddress, tokenIdentifier, amount, signer)
+}
+
+// SendWithContext builds, signs and submits a transfer and returns its
+// transaction ID. Failures are reported as a *common.SendError naming the
+// stage that failed.
+func (s *ServerAdapter) SendWithContext(ctx context.Context, accountId, toAddress, tokenIdentifier string, amount uint64, signer Signer) (string, error) {
+	tpl, err := s.BuildTransactionWithContext(ctx, accountId, toAddress, tokenIdentifier, amount)
+	if err != nil {
+		return "", &common.SendError{Stage: common.StageBuild, Err: err}
+	}
+
+	return s.signAndSubmit(ctx, tpl, signer)
+}
+
+func (s *ServerAdapter) signAndSubmit(ctx context.Context, tpl *internal.BuildTransactionResp, signer Signer) (string, error) {
+	rawTx, err := signer(ctx, tpl)
+	if err != nil {
+		return "", &common.SendError{Stage: common.StageSign, Err: err}
+	}
+
+	txId, err := s.SubmitTransactionWithContext(ctx, rawTx)
+	if err != nil {
+		return "", &common.SendError{Stage: common.StageSubmit, Err: err}
+	}
+	return txId, nil
+}
@@ -1,60 +1,131 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/bytom/vapor/consensus"
-	"github.com/bytom/vapor/errors"
+	"github.com/pkg/errors"
 
 	"vapor-adapter/common"
 	"vapor-adapter/internal"
 	"vapor-adapter/types"
 )
 
+// txPageSize is the number of transactions asked for per list-transactions
+// page when an account is read in full.
+const txPageSize = 100
+
 type ServerAdapter struct {
-	nodeAddr    string
-	chainId     string
-	netParams   *consensus.Params
-	accessToken string
+	nodes         *nodePool
+	walletNode    string
+	pinned        map[string]bool
+	chainId       string
+	netParams     *consensus.Params
+	accessToken   string
+	timeout       time.Duration
+	httpClient    *http.Client
+	header        map[string]string
+	retryPolicy   *RetryPolicy
+	observer      func(CallInfo)
+	tokens        *common.TokenRegistry
+	unknownAssets common.UnknownAssetMode
+	assetRetry    time.Duration
+	assetMisses   *assetMisses
+	assetObserver func(assetId string, err error)
+	maxTxInputs   int
+	maxTxOutputs  int
+	directory     *AccountDirectory
+
+	reuseXPubAccounts bool
 }
 
-func NewServerAdapter(chainId, nodeAddr, accessToken string) (*ServerAdapter, error) {
+func NewServerAdapter(chainId, nodeAddr, accessToken string, opts ...ServerOption) (*ServerAdapter, error) {
 	netParams, ok := consensus.NetParams[chainId]
 	if !ok {
 		return nil, errors.New(fmt.Sprintf("%s does not exist", chainId))
 	}
 
-	return &ServerAdapter{nodeAddr: nodeAddr, chainId: chainId, netParams: &netParams, accessToken: accessToken}, nil
+	s := &ServerAdapter{
+		nodes:        newNodePool(nodeAddr),
+		walletNode:   nodeAddr,
+		pinned:       make(map[string]bool),
+		chainId:      chainId,
+		netParams:    &netParams,
+		accessToken:  accessToken,
+		timeout:      common.DefaultRequestTimeout,
+		httpClient:   &http.Client{},
+		header:       make(map[string]string),
+		tokens:       common.NewTokenRegistry(),
+		assetRetry:   common.DefaultAssetRetryInterval,
+		assetMisses:  &assetMisses{failedAt: make(map[string]time.Time)},
+		maxTxInputs:  common.DefaultMaxTxInputs,
+		maxTxOutputs: common.DefaultMaxTxOutputs,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// SetTimeout changes the default deadline applied to each node request whose
+// context has no deadline. A zero or negative timeout disables it.
+func (s *ServerAdapter) SetTimeout(timeout time.Duration) {
+	s.timeout = timeout
 }
 
 func (s *ServerAdapter) PubkeyToAddress(pubkey string) (string, error) {
-	clientAdapter, err := NewClientAdapter(s.chainId)
+	clientAdapter, err := NewClientAdapter(s.chainId, WithClientTokenRegistry(s.tokens))
 	if err != nil {
 		return "", errors.Wrapf(err, "new client adapter")
 	}
 
-	return clientAdapter.PubkeyToAddress(pubkey)
+	address, err := clientAdapter.PubkeyToAddress(pubkey)
+	if err != nil {
+		return "", err
+	}
+
+	if s.directory != nil {
+		if accountId, ok := s.directory.AccountForXPub(pubkey); ok {
+			if err := s.directory.AddAddress(accountId, address); err != nil {
+				return "", err
+			}
+		}
+	}
+	return address, nil
 }
 
 func (s *ServerAdapter) GetBlockCount() (uint64, error) {
-	url := s.nodeAddr + "/get-block-count"
+	return s.GetBlockCountWithContext(context.Background())
+}
+
+func (s *ServerAdapter) GetBlockCountWithContext(ctx context.Context) (uint64, error) {
 	resp := &internal.GetBlockCountResp{}
-	return resp.BlockCount, s.RequestVapor(url, nil, resp)
+	return resp.BlockCount, s.call(ctx, "/get-block-count", nil, resp, true)
 }
 
 func (s *ServerAdapter) GetRawMemPool() ([]*types.Tx, error) {
-	url := s.nodeAddr + "/list-unconfirmed-transactions"
+	return s.GetRawMemPoolWithContext(context.Background())
+}
+
+// GetRawMemPoolWithContext returns the transactions of the mempool. Those
+// packed or dropped between listing the mempool and fetching them are left out.
+func (s *ServerAdapter) GetRawMemPoolWithContext(ctx context.Context) ([]*types.Tx, error) {
 	resp := &internal.ListUnconfirmedTxResp{}
-	if err := s.RequestVapor(url, nil, resp); err != nil {
+	if err := s.call(ctx, "/list-unconfirmed-transactions", nil, resp, true); err != nil {
 		return nil, errors.Wrapf(err, "request list unconfirmed transaction")
 	}
 
 	var txs []*types.Tx
 	for _, txId := range resp.TxIds {
-		tx, err := s.getUnconfirmedTx(txId)
-		if err != nil {
+		tx, err := s.getUnconfirmedTx(ctx, txId)
+		if errors.Is(err, common.ErrTxNotFound) {
+			continue
+		} else if err != nil {
 			return nil, err
 		}
 
@@ -64,60 +135,164 @@ func (s *ServerAdapter) GetRawMemPool() ([]*types.Tx, error) {
 }
 
 func (s *ServerAdapter) GetBlockTxs(blockNo uint64) ([]*types.Tx, error) {
-	url := s.nodeAddr + "/get-block"
-	req := &internal.GetBlockReq{BlockHeight: blockNo}
-	resp := &internal.GetBlockResp{}
-	if err := s.RequestVapor(url, req, resp); err != nil {
-		return nil, errors.Wrapf(err, "request get block")
+	return s.GetBlockTxsWithContext(context.Background(), blockNo)
+}
+
+func (s *ServerAdapter) GetBlockTxsWithContext(ctx context.Context, blockNo uint64) ([]*types.Tx, error) {
+	block, err := s.GetBlockWithContext(ctx, blockNo)
+	if err != nil {
+		return nil, err
 	}
 
-	var txs []*types.Tx
-	for _, tx := range resp.Txs {
-		temp := transformTx(tx)
-		temp.TxHash = tx.ID
-		temp.TxAt = resp.Timestamp
-		txs = append(txs, temp)
+	return block.Txs, nil
+}
+
+func (s *ServerAdapter) GetBlock(blockNo uint64) (*types.Block, error) {
+	return s.GetBlockWithContext(context.Background(), blockNo)
+}
+
+func (s *ServerAdapter) GetBlockWithContext(ctx context.Context, blockNo uint64) (*types.Block, error) {
+	bestHeight, err := s.GetBlockCountWithContext(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return txs, nil
+
+	return s.getBlock(ctx, &internal.GetBlockReq{BlockHeight: blockNo}, bestHeight)
+}
+
+func (s *ServerAdapter) GetBlockByHash(blockHash string) (*types.Block, error) {
+	return s.GetBlockByHashWithContext(context.Background(), blockHash)
+}
+
+// GetBlockByHashWithContext returns the block blockHash, which may be an
+// orphan no longer on the best chain.
+func (s *ServerAdapter) GetBlockByHashWithContext(ctx context.Context, blockHash string) (*types.Block, error) {
+	bestHeight, err := s.GetBlockCountWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getBlock(ctx, &internal.GetBlockReq{BlockHash: blockHash}, bestHeight)
 }
 
 func (s *ServerAdapter) GetTransaction(txHash string) (*types.Tx, error) {
-	url := s.nodeAddr + "/get-transaction"
+	return s.GetTransactionWithContext(context.Background(), txHash)
+}
+
+func (s *ServerAdapter) GetTransactionWithContext(ctx context.Context, txHash string) (*types.Tx, error) {
 	req := &internal.GetTxReq{TxId: txHash}
 	resp := &internal.Transaction{}
-	if err := s.RequestVapor(url, req, resp); err != nil {
+	if err := s.call(ctx, "/get-transaction", req, resp, true); err != nil {
 		return nil, errors.Wrapf(err, "request get transaction")
 	}
 
-	transaction := transformTx(resp)
+	bestHeight, err := s.bestHeightFor(ctx, []*internal.Transaction{resp})
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := s.transformTx(ctx, resp)
 	transaction.TxHash = resp.TxId
 	transaction.TxAt = resp.BlockTime
+	transaction.Confirmations = confirmations(transaction, bestHeight)
 	return transaction, nil
 }
 
 func (s *ServerAdapter) CreateAccount(rootXPub, accountAlias string) (string, error) {
-	url := s.nodeAddr + "/create-account"
-	req := &internal.CreateAccountReq{RootXpubs: []string{rootXPub}, Quorum: 1, Alias: accountAlias}
-	resp := &internal.CreateAccountResp{}
-	if err := s.RequestVapor(url, req, resp); err != nil {
-		return "", errors.Wrapf(err, "request create account")
+	return s.CreateAccountWithContext(context.Background(), rootXPub, accountAlias)
+}
+
+// CreateAccountWithContext creates the account accountAlias of the single key
+// rootXPub, or returns it when the alias already names the account of
+// rootXPub. With WithXPubAccountReuse any existing account of rootXPub alone is
+// returned first, whatever its alias.
+func (s *ServerAdapter) CreateAccountWithContext(ctx context.Context, rootXPub, accountAlias string) (string, error) {
+	rootXPubs := []string{rootXPub}
+	if s.reuseXPubAccounts {
+		accountId, err := s.xpubAccount(ctx, rootXPubs)
+		if err != nil || accountId != "" {
+			return accountId, err
+		}
 	}
 
-	return resp.AccountId, nil
+	return s.createAccount(ctx, rootXPubs, 1, accountAlias)
+}
+
+func (s *ServerAdapter) CreateAddress(accountId string) (string, error) {
+	return s.CreateAddressWithContext(context.Background(), accountId)
+}
+
+// CreateAddressWithContext creates a new address of accountId on the node and
+// records it in the account directory of the adapter, if any.
+func (s *ServerAdapter) CreateAddressWithContext(ctx context.Context, accountId string) (string, error) {
+	receiver, err := s.createReceiver(ctx, accountId)
+	if err != nil {
+		return "", err
+	}
+
+	return receiver.Address, nil
 }
 
 func (s *ServerAdapter) BalancesForAddress(accountId string) ([]*types.Balance, error) {
-	url := s.nodeAddr + "/list-balances"
+	return s.BalancesForAddressWithContext(context.Background(), accountId)
+}
+
+// BalancesForAddressWithContext returns the balances of the account accountId,
+// or of a single address when accountId is an address recorded in the account
+// directory of the adapter.
+func (s *ServerAdapter) BalancesForAddressWithContext(ctx context.Context, accountId string) ([]*types.Balance, error) {
+	if accountId, address, ok := s.resolveAddress(accountId); ok {
+		return s.addressBalances(ctx, accountId, address)
+	}
+
 	req := &internal.ListBalanceReq{AccountId: accountId}
 	var resp []*internal.Balance
-	if err := s.RequestVapor(url, req, &resp); err != nil {
+	if err := s.call(ctx, "/list-balances", req, &resp, true); err != nil {
 		return nil, errors.Wrapf(err, "request list balances")
 	}
 
+	return s.transformBalances(ctx, resp), nil
+}
+
+// addressBalances sums the unspent outputs of accountId controlled by address.
+func (s *ServerAdapter) addressBalances(ctx context.Context, accountId, address string) ([]*types.Balance, error) {
+	req := &internal.ListUnspentReq{AccountId: accountId}
+	var resp []*internal.UnspentOutput
+	if err := s.call(ctx, "/list-unspent-outputs", req, &resp, true); err != nil {
+		return nil, errors.Wrapf(err, "request list unspent outputs")
+	}
+
+	var sums []*internal.Balance
+	byAsset := make(map[string]*internal.Balance)
+	for _, utxo := range resp {
+		if utxo.Address != address {
+			continue
+		}
+
+		sum, ok := byAsset[utxo.AssetId]
+		if !ok {
+			sum = &internal.Balance{AssetId: utxo.AssetId}
+			byAsset[utxo.AssetId] = sum
+			sums = append(sums, sum)
+		}
+		sum.Amount += utxo.Amount
+	}
+	return s.transformBalances(ctx, sums), nil
+}
+
+func (s *ServerAdapter) transformBalances(ctx context.Context, resp []*internal.Balance) []*types.Balance {
+	if s.unknownAssets == common.UnknownAssetLookup {
+		var assetIds []string
+		for _, b := range resp {
+			assetIds = append(assetIds, b.AssetId)
+		}
+		s.loadUnknownAssets(ctx, assetIds)
+	}
+
 	var balances []*types.Balance
 	for _, b := range resp {
-		tokenParams, ok := common.TokenParams[b.AssetId]
-		if !ok {
+		tokenParams, ok := s.tokens.Get(b.AssetId)
+		if !ok && s.unknownAssets == common.UnknownAssetDrop {
 			continue
 		}
 		balance := &types.Balance{
@@ -125,60 +300,195 @@ func (s *ServerAdapter) BalancesForAddress(accountId string) ([]*types.Balance,
 			TokenIdentifier: b.AssetId,
 			TokenCode:       tokenParams.Code,
 			TokenDecimal:    tokenParams.Decimal,
+			Unknown:         !ok,
 		}
 		balances = append(balances, balance)
 	}
-	return balances, nil
+	return balances
 }
 
 func (s *ServerAdapter) TxsForAddress(accountId string, start, limit int) ([]*types.Tx, error) {
-	url := s.nodeAddr + "/list-transactions"
-	req := &internal.ListTxReq{AccountId: accountId, Detail: true, From: start, Count: limit}
+	return s.TxsForAddressWithContext(context.Background(), accountId, start, limit)
+}
+
+// TxsForAddressWithContext returns limit transactions of the account accountId
+// from start on, or of a single address when accountId is an address recorded
+// in the account directory of the adapter. The node pages an account itself;
+// the transactions of an address are picked out of every transaction of its
+// account, newest first, and a zero limit returns all of them.
+func (s *ServerAdapter) TxsForAddressWithContext(ctx context.Context, accountId string, start, limit int) ([]*types.Tx, error) {
+	accountId, address, byAddress := s.resolveAddress(accountId)
+
 	var resp []*internal.Transaction
-	if err := s.RequestVapor(url, req, &resp); err != nil {
-		return nil, errors.Wrapf(err, "request list balances")
+	if byAddress {
+		all, err := s.accountTxs(ctx, accountId)
+		if err != nil {
+			return nil, err
+		}
+
+		resp = pageTxs(filterTxs(all, address), start, limit)
+	} else {
+		req := &internal.ListTxReq{AccountId: accountId, Detail: true, From: start, Count: limit}
+		if err := s.call(ctx, "/list-transactions", req, &resp, true); err != nil {
+			return nil, errors.Wrapf(err, "request list balances")
+		}
+	}
+
+	bestHeight, err := s.bestHeightFor(ctx, resp)
+	if err != nil {
+		return nil, err
 	}
 
 	var txs []*types.Tx
 	for _, tx := range resp {
-		temp := transformTx(tx)
+		temp := s.transformTx(ctx, tx)
 		temp.TxHash = tx.TxId
 		temp.TxAt = tx.BlockTime
+		temp.Confirmations = confirmations(temp, bestHeight)
 		txs = append(txs, temp)
 	}
 	return txs, nil
 }
 
-func (s *ServerAdapter) BuildTransaction(accountId, toAddress, tokenIdentifier string, amount uint64) (*internal.BuildTransactionResp, error) {
-	url := s.nodeAddr + "/build-transaction"
-	var actions []*internal.Actions
-	spendAction := &internal.Actions{
-		AccountId: accountId,
-		Amount:    amount,
-		AssetId:   tokenIdentifier,
-		Type:      "spend_account",
+// resolveAddress returns the account of addressOrAccountId when it is an
+// address recorded in the account directory.
+func (s *ServerAdapter) resolveAddress(addressOrAccountId string) (accountId, address string, ok bool) {
+	if s.directory != nil {
+		if accountId, ok := s.directory.AccountForAddress(addressOrAccountId); ok {
+			return accountId, addressOrAccountId, true
+		}
 	}
-	actions = append(actions, spendAction)
+	return addressOrAccountId, "", false
+}
+
+// filterTxs returns the transactions with an input or an output of address.
+func filterTxs(transactions []*internal.Transaction, address string) []*internal.Transaction {
+	var filtered []*internal.Transaction
+	for _, transaction := range transactions {
+		found := false
+		for _, input := range transaction.Inputs {
+			found = found || input.Address == address
+		}
+		for _, output := range transaction.Outputs {
+			found = found || output.Address == address
+		}
+		if found {
+			filtered = append(filtered, transaction)
+		}
+	}
+	return filtered
+}
+
+// accountTxs returns every transaction of accountId. The node ignores from and
+// returns no transaction for a zero count, so the account is paged with
+// start_tx_id, each page starting after the last transaction of the previous
+// one, until a page comes back short.
+func (s *ServerAdapter) accountTxs(ctx context.Context, accountId string) ([]*internal.Transaction, error) {
+	req := &internal.ListTxReq{AccountId: accountId, Detail: true, Count: txPageSize}
+	seen := make(map[string]bool)
+	var txs []*internal.Transaction
+	for {
+		var page []*internal.Transaction
+		if err := s.call(ctx, "/list-transactions", req, &page, true); err != nil {
+			return nil, errors.Wrapf(err, "request list transactions")
+		}
+
+		// the node sorts a page by height, so a page may repeat a few
+		// transactions of the same block
+		for _, tx := range page {
+			if !seen[tx.TxId] {
+				seen[tx.TxId] = true
+				txs = append(txs, tx)
+			}
+		}
 
-	controlAction := &internal.Actions{
-		Amount:  amount,
-		AssetId: tokenIdentifier,
-		Type:    "control_address",
-		Address: toAddress,
+		if len(page) < txPageSize {
+			return txs, nil
+		}
+		req.StartTxId = page[len(page)-1].TxId
+	}
+}
+
+// pageTxs returns limit transactions from start on, all of them for a zero
+// start and limit.
+func pageTxs(transactions []*internal.Transaction, start, limit int) []*internal.Transaction {
+	if start == 0 && limit == 0 {
+		return transactions
+	}
+	if start >= len(transactions) {
+		return nil
 	}
-	actions = append(actions, controlAction)
 
-	req := &internal.BuildTransactionReq{Actions: actions}
+	end := start + limit
+	if limit <= 0 || end > len(transactions) {
+		end = len(transactions)
+	}
+	return transactions[start:end]
+}
+
+func (s *ServerAdapter) BuildTransaction(accountId, toAddress, tokenIdentifier string, amount uint64) (*internal.BuildTransactionResp, error) {
+	return s.BuildTransactionWithContext(context.Background(), accountId, toAddress, tokenIdentifier, amount)
+}
+
+func (s *ServerAdapter) BuildTransactionWithContext(ctx context.Context, accountId, toAddress, tokenIdentifier string, amount uint64) (*internal.BuildTransactionResp, error) {
+	actions := transferActions(accountId, toAddress, tokenIdentifier, amount)
+	return s.buildTransaction(ctx, &internal.BuildTransactionReq{Actions: actions})
+}
+
+func (s *ServerAdapter) buildTransaction(ctx context.Context, req *internal.BuildTransactionReq) (*internal.BuildTransactionResp, error) {
 	resp := &internal.BuildTransactionResp{}
-	if err := s.RequestVapor(url, req, resp); err != nil {
+	if err := s.call(ctx, "/build-transaction", req, resp, false); err != nil {
 		return nil, errors.Wrapf(err, "request build transaction")
 	}
 
 	return resp, nil
 }
 
+func (s *ServerAdapter) SignTransaction(tpl *internal.BuildTransactionResp, password string) (*internal.BuildTransactionResp, bool, error) {
+	return s.SignTransactionWithContext(context.Background(), tpl, password)
+}
+
+// SignTransactionWithContext signs tpl with the keys the node holds for it and
+// reports whether every signature required by the template is now present.
+func (s *ServerAdapter) SignTransactionWithContext(ctx context.Context, tpl *internal.BuildTransactionResp, password string) (*internal.BuildTransactionResp, bool, error) {
+	req := &internal.SignTransactionReq{Password: password, Transaction: tpl}
+	resp := &internal.SignTransactionResp{}
+	if err := s.call(ctx, "/sign-transaction", req, resp, false); err != nil {
+		return nil, false, errors.Wrapf(err, "request sign transaction")
+	}
+
+	return resp.Transaction, resp.SignComplete, nil
+}
+
+func (s *ServerAdapter) SubmitTransaction(rawSignedTx string) (string, error) {
+	return s.SubmitTransactionWithContext(context.Background(), rawSignedTx)
+}
+
+// SubmitTransactionWithContext broadcasts a signed raw transaction and returns
+// its transaction ID.
+func (s *ServerAdapter) SubmitTransactionWithContext(ctx context.Context, rawSignedTx string) (string, error) {
+	req := &internal.SubmitTransactionReq{RawTransaction: rawSignedTx}
+	resp := &internal.SubmitTransactionResp{}
+	if err := s.call(ctx, "/submit-transaction", req, resp, false); err != nil {
+		return "", errors.Wrapf(err, "request submit transaction")
+	}
+
+	return resp.TxId, nil
+}
+
 func (s *ServerAdapter) RequestVapor(url string, req interface{}, resp interface{}) error {
+	return s.RequestVaporWithContext(context.Background(), url, req, resp)
+}
+
+// RequestVaporWithContext posts req to the node and decodes the data field of a
+// successful response into resp. The default timeout of the adapter is applied
+// unless ctx already carries a deadline.
+func (s *ServerAdapter) RequestVaporWithContext(ctx context.Context, url string, req interface{}, resp interface{}) error {
 	header := make(map[string]string)
+	for k, v := range s.header {
+		header[k] = v
+	}
+
 	header, err := setAccessToken(header, s.accessToken)
 	if err != nil {
 		return err
@@ -189,72 +499,160 @@ func (s *ServerAdapter) RequestVapor(url string, req interface{}, resp interface
 		return err
 	}
 
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	result := &internal.Response{}
-	if err := common.Post(url, header, payload, result); err != nil {
+	if err := common.PostWithContext(ctx, s.httpClient, url, header, payload, result); err != nil {
 		return err
 	}
 
 	if result.Status != "success" {
-		return errors.New(result.ErrDetail)
+		return &common.NodeError{
+			Code:       result.Code,
+			Message:    result.Msg,
+			Detail:     result.ErrDetail,
+			HTTPStatus: http.StatusOK,
+			Endpoint:   url,
+		}
 	}
 
 	return json.Unmarshal(result.Data, resp)
 }
 
-func (s *ServerAdapter) getUnconfirmedTx(txId string) (*types.Tx, error) {
-	url := s.nodeAddr + "/get-unconfirmed-transaction"
+// getBlock returns the block of req with the confirmations of its transactions
+// counted at bestHeight, so callers that already know the best height do not
+// ask the node for it again.
+func (s *ServerAdapter) getBlock(ctx context.Context, req *internal.GetBlockReq, bestHeight uint64) (*types.Block, error) {
+	resp := &internal.GetBlockResp{}
+	if err := s.call(ctx, "/get-block", req, resp, true); err != nil {
+		return nil, errors.Wrapf(err, "request get block")
+	}
+
+	block := &types.Block{Height: resp.Height, Hash: resp.Hash, PreviousHash: resp.PreviousBlockHash, Timestamp: resp.Timestamp}
+	for i, tx := range resp.Txs {
+		tx.BlockHash, tx.BlockHeight, tx.BlockIndex = resp.Hash, resp.Height, uint32(i)
+		temp := s.transformTx(ctx, tx)
+		temp.TxHash = tx.ID
+		temp.TxAt = resp.Timestamp
+		temp.Confirmations = confirmations(temp, bestHeight)
+		block.Txs = append(block.Txs, temp)
+	}
+	return block, nil
+}
+
+func (s *ServerAdapter) getUnconfirmedTx(ctx context.Context, txId string) (*types.Tx, error) {
 	req := &internal.GetUnconfirmedTxReq{TxId: txId}
 	resp := &internal.Transaction{}
-	if err := s.RequestVapor(url, req, resp); err != nil {
+	if err := s.call(ctx, "/get-unconfirmed-transaction", req, resp, true); err != nil {
 		return nil, errors.Wrapf(err, "request get unconfirmed transaction")
 	}
 
-	transaction := transformTx(resp)
+	transaction := s.transformTx(ctx, resp)
 	transaction.TxHash = resp.ID
 	return transaction, nil
 }
 
-func transformTx(transaction *internal.Transaction) *types.Tx {
-	inputs := transformInput(transaction)
-	outputs := transformOutput(transaction)
-	return &types.Tx{Inputs: inputs, Outputs: outputs}
+func (s *ServerAdapter) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.timeout)
 }
 
-func transformInput(transaction *internal.Transaction) []*types.UTXO {
-	var inputs []*types.UTXO
+func (s *ServerAdapter) transformTx(ctx context.Context, transaction *internal.Transaction) *types.Tx {
+	if s.unknownAssets == common.UnknownAssetLookup {
+		var assetIds []string
+		for _, input := range transaction.Inputs {
+			assetIds = append(assetIds, input.AssetId)
+		}
+		for _, output := range transaction.Outputs {
+			assetIds = append(assetIds, output.AssetId)
+		}
+		s.loadUnknownAssets(ctx, assetIds)
+	}
+
+	return &types.Tx{
+		Inputs:      transformInput(transaction, s.tokens, s.unknownAssets),
+		Outputs:     transformOutput(transaction, s.tokens, s.unknownAssets),
+		BlockHeight: transaction.BlockHeight,
+		BlockHash:   transaction.BlockHash,
+		Position:    transaction.BlockIndex,
+		Fee:         transactionFee(transaction),
+		StatusFail:  transaction.StatusFail,
+		Size:        transaction.Size,
+	}
+}
+
+// transactionFee returns the amount of every asset the inputs of transaction
+// spend beyond its outputs.
+func transactionFee(transaction *internal.Transaction) map[string]uint64 {
+	balances := make(map[string]int64)
 	for _, input := range transaction.Inputs {
-		tokenParams, ok := common.TokenParams[input.AssetId]
-		if !ok {
-			//ignore invalid token
-			continue
+		balances[input.AssetId] += int64(input.Amount)
+	}
+	for _, output := range transaction.Outputs {
+		balances[output.AssetId] -= int64(output.Amount)
+	}
+
+	fee := make(map[string]uint64)
+	for assetId, balance := range balances {
+		if balance > 0 {
+			fee[assetId] = uint64(balance)
 		}
-		utxo := &types.UTXO{
-			Address:         input.Address,
-			Value:           input.Amount,
-			TokenIdentifier: input.AssetId,
-			TokenCode:       tokenParams.Code,
-			TokenDecimal:    tokenParams.Decimal,
+	}
+	return fee
+}
+
+// bestHeightFor returns the best height to count the confirmations of txs at,
+// asking the node only when one of them is in a block.
+func (s *ServerAdapter) bestHeightFor(ctx context.Context, txs []*internal.Transaction) (uint64, error) {
+	for _, tx := range txs {
+		if tx.BlockHash != "" {
+			return s.GetBlockCountWithContext(ctx)
 		}
+	}
+	return 0, nil
+}
+
+// confirmations returns the number of blocks confirming tx at bestHeight.
+func confirmations(tx *types.Tx, bestHeight uint64) uint64 {
+	if tx.BlockHash == "" || tx.BlockHeight > bestHeight {
+		return 0
+	}
+	return bestHeight - tx.BlockHeight + 1
+}
+
+func transformInput(transaction *internal.Transaction, tokens *common.TokenRegistry, mode common.UnknownAssetMode) []*types.UTXO {
+	var inputs []*types.UTXO
+	for i, input := range transaction.Inputs {
+		utxo := newUTXO(tokens, mode, input.Address, input.Amount, input.AssetId)
+		if utxo == nil {
+			continue
+		}
+
+		utxo.Type = input.Type
+		utxo.SpentOutputID = input.SpentOutputId
+		utxo.Index = i
+		utxo.ControlProgram = input.ControlProgram
 		inputs = append(inputs, utxo)
 	}
 	return inputs
 }
 
-func transformOutput(transaction *internal.Transaction) []*types.UTXO {
+func transformOutput(transaction *internal.Transaction, tokens *common.TokenRegistry, mode common.UnknownAssetMode) []*types.UTXO {
 	var outputs []*types.UTXO
-	for _, output := range transaction.Outputs {
-		tokenParams, ok := common.TokenParams[output.AssetId]
-		if !ok {
-			//ignore invalid token
+	for i, output := range transaction.Outputs {
+		utxo := newUTXO(tokens, mode, output.Address, output.Amount, output.AssetId)
+		if utxo == nil {
 			continue
 		}
-		utxo := &types.UTXO{
-			Address:         output.Address,
-			Value:           output.Amount,
-			TokenIdentifier: output.AssetId,
-			TokenCode:       tokenParams.Code,
-			TokenDecimal:    tokenParams.Decimal,
-		}
+
+		utxo.Type = output.Type
+		utxo.Vote = output.Vote
+		utxo.OutputID = output.OutputId
+		utxo.Index = i
+		utxo.ControlProgram = output.ControlProgram
 		outputs = append(outputs, utxo)
 	}
 	return outputs
@@ -1,9 +1,30 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/bytom/vapor/consensus"
+	"github.com/bytom/vapor/crypto/ed25519/chainkd"
+	"github.com/bytom/vapor/protocol/bc"
+	vaporTypes "github.com/bytom/vapor/protocol/bc/types"
+
+	"vapor-adapter/common"
 	"vapor-adapter/internal"
 	"vapor-adapter/types"
 )
@@ -269,3 +290,1685 @@ func TestServerAdapter_TxsForAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestServerAdapter_GetBlockCountWithContext(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/slow") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(`{"status":"success","data":{"block_count":6876}}`))
+	}))
+	defer node.Close()
+
+	tests := []struct {
+		name     string
+		nodeAddr string
+		timeout  time.Duration
+		want     uint64
+		wantErr  bool
+	}{
+		{name: "success", nodeAddr: node.URL, timeout: time.Second, want: uint64(6876), wantErr: false},
+		{name: "deadline exceeded", nodeAddr: node.URL + "/slow", timeout: 50 * time.Millisecond, want: uint64(0), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := NewServerAdapter("testnet", tt.nodeAddr, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			got, err := adapter.GetBlockCountWithContext(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetBlockCountWithContext() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetBlockCountWithContext() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type countingTransport struct {
+	count int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.count++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewServerAdapter_Options(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"block_count":1}}`))
+	}))
+	defer node.Close()
+
+	transport := &countingTransport{}
+	tests := []struct {
+		name      string
+		opts      []ServerOption
+		wantCount int
+		wantErr   bool
+	}{
+		{name: "missing header", opts: []ServerOption{WithTransport(transport)}, wantCount: 1, wantErr: true},
+		{name: "custom header", opts: []ServerOption{WithTransport(transport), WithHeader("X-Api-Key", "secret")}, wantCount: 2, wantErr: false},
+		{name: "custom client", opts: []ServerOption{WithHTTPClient(&http.Client{Transport: transport}), WithHeader("X-Api-Key", "secret")}, wantCount: 3, wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := NewServerAdapter("testnet", node.URL, "", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := adapter.GetBlockCount(); (err != nil) != tt.wantErr {
+				t.Errorf("GetBlockCount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if transport.count != tt.wantCount {
+				t.Errorf("RoundTrip() count = %v, want %v", transport.count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_RetryPolicy(t *testing.T) {
+	var calls int
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"block_count":1}}`))
+	}))
+	defer node.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	tests := []struct {
+		name      string
+		call      func(adapter *ServerAdapter) error
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "read is retried",
+			call:      func(adapter *ServerAdapter) error { _, err := adapter.GetBlockCount(); return err },
+			wantCalls: 3,
+			wantErr:   false,
+		},
+		{
+			name:      "build is not retried",
+			call:      func(adapter *ServerAdapter) error { _, err := adapter.BuildTransaction("", "", "", 1); return err },
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			adapter, err := NewServerAdapter("testnet", node.URL, "", WithRetryPolicy(policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.call(adapter); (err != nil) != tt.wantErr {
+				t.Errorf("call error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	refused := &url.Error{Op: "Post", URL: "http://127.0.0.1:1", Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}
+	reset := &url.Error{Op: "Post", URL: "http://127.0.0.1:1", Err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}
+	timeout := &url.Error{Op: "Post", URL: "http://127.0.0.1:1", Err: &net.DNSError{IsTimeout: true}}
+	scheme := &url.Error{Op: "Post", URL: "ftp://127.0.0.1:1", Err: errors.New("unsupported protocol scheme \"ftp\"")}
+	badGateway := &common.NodeError{HTTPStatus: http.StatusBadGateway}
+
+	tests := []struct {
+		name          string
+		maxBackoff    time.Duration
+		wantBackoff   []time.Duration
+		err           error
+		wantRetryable bool
+	}{
+		{name: "capped", maxBackoff: 3 * time.Millisecond, wantBackoff: []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 3 * time.Millisecond}, err: refused, wantRetryable: true},
+		{name: "uncapped", wantBackoff: []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 8 * time.Millisecond}, err: reset, wantRetryable: true},
+		{name: "timeout", err: timeout, wantRetryable: true},
+		{name: "gateway", err: badGateway, wantRetryable: true},
+		{name: "bad scheme", err: scheme, wantRetryable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultRetryPolicy()
+			policy.InitialBackoff, policy.MaxBackoff, policy.Jitter = time.Millisecond, tt.maxBackoff, 0
+			for i, want := range tt.wantBackoff {
+				if got := policy.backoff(i + 1); got != want {
+					t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+				}
+			}
+			if got := policy.isRetryable(tt.err); got != tt.wantRetryable {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_Failover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	syncing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/net-info" {
+			w.Write([]byte(`{"status":"success","data":{"syncing":true,"current_block":10}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"block_count":10}}`))
+	}))
+	defer syncing.Close()
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/net-info" {
+			w.Write([]byte(`{"status":"success","data":{"syncing":false,"current_block":20}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"block_count":20}}`))
+	}))
+	defer up.Close()
+
+	tests := []struct {
+		name      string
+		check     bool
+		want      uint64
+		wantNodes []string
+	}{
+		{name: "fail over in order", check: false, want: 10, wantNodes: []string{down.URL, syncing.URL}},
+		{name: "route to healthiest", check: true, want: 20, wantNodes: []string{up.URL}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var served []string
+			observer := func(info CallInfo) { served = append(served, info.Node) }
+			adapter, err := NewServerAdapter("testnet", down.URL, "", WithNodes(syncing.URL, up.URL), WithCallObserver(observer))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.check {
+				adapter.CheckNodes(context.Background())
+			}
+			got, err := adapter.GetBlockCount()
+			if err != nil {
+				t.Fatalf("GetBlockCount() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetBlockCount() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(served, tt.wantNodes) {
+				t.Errorf("served by = %v, want %v", served, tt.wantNodes)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_NodeRecovery(t *testing.T) {
+	var flakyCalls int
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flakyCalls++
+		if flakyCalls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"block_count":10}}`))
+	}))
+	defer flaky.Close()
+
+	stable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","data":{"block_count":10}}`))
+	}))
+	defer stable.Close()
+
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		wantNodes   []string
+		wantHealthy bool
+	}{
+		{name: "routed last", interval: time.Hour, wantNodes: []string{stable.URL}, wantHealthy: false},
+		{name: "tried again", interval: 0, wantNodes: []string{flaky.URL}, wantHealthy: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flakyCalls = 0
+			var served []string
+			observer := func(info CallInfo) { served = append(served, info.Node) }
+			adapter, err := NewServerAdapter("testnet", flaky.URL, "", WithNodes(stable.URL), WithNodeRetryInterval(tt.interval), WithCallObserver(observer))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := adapter.GetBlockCount(); err != nil {
+				t.Fatalf("GetBlockCount() error = %v", err)
+			}
+
+			served = nil
+			if _, err := adapter.GetBlockCount(); err != nil {
+				t.Fatalf("GetBlockCount() error = %v", err)
+			}
+			if !reflect.DeepEqual(served, tt.wantNodes) {
+				t.Errorf("served by = %v, want %v", served, tt.wantNodes)
+			}
+			if got := adapter.NodeStatuses()[0].Healthy; got != tt.wantHealthy {
+				t.Errorf("flaky node healthy = %v, want %v", got, tt.wantHealthy)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_WalletNode(t *testing.T) {
+	newNode := func(height uint64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/net-info":
+				w.Write([]byte(`{"status":"success","data":{"syncing":false,"current_block":` + strconv.FormatUint(height, 10) + `}}`))
+			case "/get-block-count":
+				w.Write([]byte(`{"status":"success","data":{"block_count":` + strconv.FormatUint(height, 10) + `}}`))
+			case "/list-balances":
+				w.Write([]byte(`{"status":"success","data":[]}`))
+			}
+		}))
+	}
+	wallet, chain := newNode(10), newNode(20)
+	defer wallet.Close()
+	defer chain.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	tests := []struct {
+		name       string
+		walletAddr string
+		opts       []ServerOption
+		wantCount  []string
+		wantWallet []string
+		wantErr    bool
+	}{
+		{name: "chain reads routed", walletAddr: wallet.URL, wantCount: []string{chain.URL}, wantWallet: []string{wallet.URL}},
+		{name: "pinned path", walletAddr: wallet.URL, opts: []ServerOption{WithPinnedPaths("/get-block-count")}, wantCount: []string{wallet.URL}, wantWallet: []string{wallet.URL}},
+		{name: "wallet node option", walletAddr: chain.URL, opts: []ServerOption{WithWalletNode(wallet.URL)}, wantCount: []string{chain.URL}, wantWallet: []string{wallet.URL}},
+		{name: "no wallet failover", walletAddr: down.URL, wantCount: []string{chain.URL}, wantWallet: []string{down.URL}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var served []string
+			observer := func(info CallInfo) { served = append(served, info.Node) }
+			opts := append([]ServerOption{WithNodes(wallet.URL, chain.URL), WithCallObserver(observer), WithRetryPolicy(&RetryPolicy{MaxAttempts: 1})}, tt.opts...)
+			adapter, err := NewServerAdapter("testnet", tt.walletAddr, "", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			adapter.CheckNodes(context.Background())
+
+			if _, err := adapter.GetBlockCount(); err != nil {
+				t.Fatalf("GetBlockCount() error = %v", err)
+			}
+			if !reflect.DeepEqual(served, tt.wantCount) {
+				t.Errorf("GetBlockCount() served by = %v, want %v", served, tt.wantCount)
+			}
+
+			served = nil
+			if _, err := adapter.BalancesForAddress("acc"); (err != nil) != tt.wantErr {
+				t.Fatalf("BalancesForAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(served, tt.wantWallet) {
+				t.Errorf("BalancesForAddress() served by = %v, want %v", served, tt.wantWallet)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_NodeError(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/build-transaction":
+			w.Write([]byte(`{"status":"fail","code":"BTM700","msg":"Funds of account are insufficient","error_detail":"accumulated utxo amount 1 is less than 100000000"}`))
+		case "/get-transaction":
+			w.Write([]byte(`{"status":"fail","code":"BTM000","msg":"Bytom API Error","error_detail":"No transaction(tx_id=11ca) "}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"BTM860","msg":"Request could not be authenticated"}`))
+		}
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		call       func() error
+		want       error
+		wantCode   string
+		wantStatus int
+	}{
+		{
+			name:       "insufficient funds",
+			call:       func() error { _, err := adapter.BuildTransaction("", "", "", 100000000); return err },
+			want:       common.ErrInsufficientFunds,
+			wantCode:   "BTM700",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "tx not found",
+			call:       func() error { _, err := adapter.GetTransaction("11ca"); return err },
+			want:       common.ErrTxNotFound,
+			wantCode:   "BTM000",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unauthenticated",
+			call:       func() error { _, err := adapter.GetBlockCount(); return err },
+			want:       common.ErrUnauthenticated,
+			wantCode:   "BTM860",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+
+			var nodeErr *common.NodeError
+			if !errors.As(err, &nodeErr) {
+				t.Fatalf("error = %v, want *common.NodeError", err)
+			}
+			if nodeErr.Code != tt.wantCode || nodeErr.HTTPStatus != tt.wantStatus {
+				t.Errorf("NodeError = %+v, want code %v status %v", nodeErr, tt.wantCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_LoadAssets(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/list-assets":
+			w.Write([]byte(`{"status":"success","data":[{"id":"3a3a","alias":"dai","definition":{"decimals":18,"symbol":"DAI"}},{"id":"4b4b","alias":"raw","definition":{}}]}`))
+		case "/get-block-count":
+			w.Write([]byte(`{"status":"success","data":{"block_count":1}}`))
+		case "/get-transaction":
+			w.Write([]byte(`{"status":"success","data":{"tx_id":"11ca","block_time":1,"inputs":[],"outputs":[{"address":"tp1q","amount":5,"asset_id":"3a3a"},{"address":"tp1q","amount":6,"asset_id":"4b4b"}]}}`))
+		}
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := adapter.LoadAssets(context.Background())
+	if err != nil {
+		t.Fatalf("LoadAssets() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("LoadAssets() got = %v, want %v", count, 1)
+	}
+
+	got, err := adapter.GetTransaction("11ca")
+	if err != nil {
+		t.Fatalf("GetTransaction() error = %v", err)
+	}
+	want := []*types.UTXO{{Address: "tp1q", Value: 5, TokenIdentifier: "3a3a", TokenCode: "DAI", TokenDecimal: 18}}
+	if !reflect.DeepEqual(got.Outputs, want) {
+		t.Errorf("GetTransaction() outputs = %v, want %v", got.Outputs, want)
+	}
+}
+
+func TestServerAdapter_UnknownAssetMode(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/get-asset":
+			w.Write([]byte(`{"status":"success","data":{"id":"3a3a","alias":"dai","definition":{"decimals":18,"symbol":"DAI"}}}`))
+		case "/get-block-count":
+			w.Write([]byte(`{"status":"success","data":{"block_count":1}}`))
+		case "/get-transaction":
+			w.Write([]byte(`{"status":"success","data":{"tx_id":"11ca","block_time":1,"inputs":[],"outputs":[{"address":"tp1q","amount":5,"asset_id":"3a3a"}]}}`))
+		}
+	}))
+	defer node.Close()
+
+	tests := []struct {
+		name string
+		mode common.UnknownAssetMode
+		want []*types.UTXO
+	}{
+		{name: "drop", mode: common.UnknownAssetDrop, want: nil},
+		{name: "keep", mode: common.UnknownAssetKeep, want: []*types.UTXO{{Address: "tp1q", Value: 5, TokenIdentifier: "3a3a", Unknown: true}}},
+		{name: "lookup", mode: common.UnknownAssetLookup, want: []*types.UTXO{{Address: "tp1q", Value: 5, TokenIdentifier: "3a3a", TokenCode: "DAI", TokenDecimal: 18}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := NewServerAdapter("testnet", node.URL, "", WithUnknownAssetMode(tt.mode))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := adapter.GetTransaction("11ca")
+			if err != nil {
+				t.Fatalf("GetTransaction() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Outputs, tt.want) {
+				t.Errorf("GetTransaction() outputs = %v, want %v", got.Outputs, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_UnknownAssetRetry(t *testing.T) {
+	var lookups []string
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/get-asset":
+			req := &internal.GetAssetReq{}
+			json.NewDecoder(r.Body).Decode(req)
+			lookups = append(lookups, req.ID)
+			if req.ID != "3a3a" {
+				w.Write([]byte(`{"status":"fail","code":"BTM000","msg":"asset not found"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"success","data":{"id":"3a3a","alias":"dai","definition":{"decimals":18,"symbol":"DAI"}}}`))
+		case "/get-block-count":
+			w.Write([]byte(`{"status":"success","data":{"block_count":1}}`))
+		case "/get-transaction":
+			w.Write([]byte(`{"status":"success","data":{"tx_id":"11ca","block_time":1,"inputs":[{"address":"tp1q","amount":7,"asset_id":"4b4b"}],"outputs":[
+				{"address":"tp1q","amount":5,"asset_id":"3a3a"},{"address":"tp1q","amount":6,"asset_id":"4b4b"},{"address":"tp1r","amount":1,"asset_id":"3a3a"}]}}`))
+		}
+	}))
+	defer node.Close()
+
+	tests := []struct {
+		name        string
+		opts        []ServerOption
+		wantLookups []string
+	}{
+		{name: "remembered", wantLookups: []string{"3a3a", "4b4b"}},
+		{name: "retried", opts: []ServerOption{WithAssetRetryInterval(0)}, wantLookups: []string{"4b4b", "3a3a", "4b4b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups = nil
+			var failed []string
+			opts := append([]ServerOption{
+				WithUnknownAssetMode(common.UnknownAssetLookup),
+				WithAssetLookupObserver(func(assetId string, err error) {
+					if err == nil {
+						t.Errorf("asset %s reported without error", assetId)
+					}
+					failed = append(failed, assetId)
+				}),
+			}, tt.opts...)
+			adapter, err := NewServerAdapter("testnet", node.URL, "", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 2; i++ {
+				got, err := adapter.GetTransaction("11ca")
+				if err != nil {
+					t.Fatalf("GetTransaction() error = %v", err)
+				}
+				if !got.Inputs[0].Unknown || got.Outputs[0].TokenCode != "DAI" {
+					t.Errorf("GetTransaction() inputs = %v, outputs = %v", got.Inputs, got.Outputs)
+				}
+			}
+
+			sort.Strings(lookups)
+			sort.Strings(tt.wantLookups)
+			if !reflect.DeepEqual(lookups, tt.wantLookups) {
+				t.Errorf("get-asset lookups = %v, want %v", lookups, tt.wantLookups)
+			}
+			if wantFailed := len(tt.wantLookups) - 1; len(failed) != wantFailed {
+				t.Errorf("reported failures = %v, want %d", failed, wantFailed)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_Send(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/build-transaction":
+			w.Write([]byte(`{"status":"success","data":{"raw_transaction":"0701","signing_instructions":[]}}`))
+		case "/submit-transaction":
+			req := &internal.SubmitTransactionReq{}
+			json.NewDecoder(r.Body).Decode(req)
+			if req.RawTransaction != "signed" {
+				w.Write([]byte(`{"status":"fail","code":"BTM716","msg":"Transaction input UTXO not found"}`))
+				return
+			}
+			w.Write([]byte(`{"status":"success","data":{"tx_id":"11ca"}}`))
+		}
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signWith := func(rawTx string, err error) Signer {
+		return func(ctx context.Context, tpl *internal.BuildTransactionResp) (string, error) {
+			return rawTx, err
+		}
+	}
+	tests := []struct {
+		name      string
+		signer    Signer
+		want      string
+		wantStage common.SendStage
+		wantErr   error
+	}{
+		{name: "success", signer: signWith("signed", nil), want: "11ca"},
+		{name: "sign failed", signer: signWith("", common.ErrNotEnoughSigs), wantStage: common.StageSign, wantErr: common.ErrNotEnoughSigs},
+		{name: "submit failed", signer: signWith("unsigned", nil), wantStage: common.StageSubmit, wantErr: common.ErrOrphanTx},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adapter.Send("acc", "tp1q", common.BTM, 1, tt.signer)
+			if tt.wantErr == nil {
+				if err != nil || got != tt.want {
+					t.Errorf("Send() got = %v, %v, want %v", got, err, tt.want)
+				}
+				return
+			}
+
+			var sendErr *common.SendError
+			if !errors.As(err, &sendErr) || sendErr.Stage != tt.wantStage {
+				t.Fatalf("Send() error = %v, want stage %v", err, tt.wantStage)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Send() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_BuildBatchTransaction(t *testing.T) {
+	rawTx := func(inputs int) string {
+		txData := vaporTypes.TxData{Version: 1}
+		for i := 0; i < inputs; i++ {
+			txData.Inputs = append(txData.Inputs, vaporTypes.NewSpendInput(nil, bc.NewHash([32]byte{byte(i)}), *consensus.BTMAssetID, 1000, 0, []byte{0x51}))
+		}
+		raw, err := vaporTypes.NewTx(txData).MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(raw)
+	}
+
+	var utxos []*internal.UnspentOutput
+	var builds [][]*internal.Actions
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/get-block-count":
+			w.Write([]byte(`{"status":"success","data":{"block_count":10}}`))
+		case "/list-unspent-outputs":
+			data, _ := json.Marshal(utxos)
+			w.Write([]byte(`{"status":"success","data":` + string(data) + `}`))
+		case "/build-transaction":
+			req := &internal.BuildTransactionReq{}
+			json.NewDecoder(r.Body).Decode(req)
+			builds = append(builds, req.Actions)
+
+			// the node picks more inputs than planned for tp1many
+			inputs := 1
+			for _, action := range req.Actions {
+				if action.Address == "tp1bad" {
+					w.Write([]byte(`{"status":"fail","code":"BTM000","msg":"Bytom API Error","error_detail":"invalid address"}`))
+					return
+				}
+				if action.Address == "tp1many" {
+					inputs = 3
+				}
+			}
+			w.Write([]byte(`{"status":"success","data":{"raw_transaction":"` + rawTx(inputs) + `","signing_instructions":[]}}`))
+		}
+	}))
+	defer node.Close()
+
+	repeat := func(amount uint64, n int) []uint64 {
+		var amounts []uint64
+		for i := 0; i < n; i++ {
+			amounts = append(amounts, amount)
+		}
+		return amounts
+	}
+
+	tests := []struct {
+		name          string
+		utxos         []uint64
+		immature      []uint64
+		maxInputs     int
+		maxOutputs    int
+		addresses     []string
+		amount        uint64
+		fee           *FeePolicy
+		wantSpends    []uint64
+		wantTxs       int
+		wantOversized int
+		wantErr       error
+		wantBatchErr  bool
+	}{
+		{name: "split by outputs", utxos: []uint64{1000, 1000, 1000}, maxInputs: 1, maxOutputs: 3, addresses: []string{"tp1q", "tp1q", "tp1q", "tp1q", "tp1q"}, amount: 100, wantSpends: []uint64{200, 200, 100}, wantTxs: 3},
+		{name: "split by inputs", utxos: repeat(100, 10), maxInputs: 2, addresses: []string{"tp1q", "tp1q", "tp1q", "tp1q"}, amount: 100, wantSpends: []uint64{200, 200}, wantTxs: 2},
+		{name: "no limits", utxos: repeat(100, 10), addresses: []string{"tp1q", "tp1q", "tp1q", "tp1q", "tp1q"}, amount: 100, wantSpends: []uint64{500}, wantTxs: 1},
+		{name: "payment over input limit", utxos: repeat(100, 5), maxInputs: 2, addresses: []string{"tp1q"}, amount: 300, wantErr: common.ErrTooManyInputs},
+		{name: "insufficient funds", utxos: []uint64{1000}, maxInputs: 1, maxOutputs: 3, addresses: []string{"tp1q", "tp1q", "tp1q", "tp1q"}, amount: 300, wantErr: common.ErrInsufficientFunds},
+		{name: "reserved by earlier chunk", utxos: []uint64{1000}, maxInputs: 1, maxOutputs: 2, addresses: []string{"tp1q", "tp1q"}, amount: 100, wantErr: common.ErrInsufficientFunds},
+		{name: "immature utxos", utxos: []uint64{100}, immature: []uint64{1000}, addresses: []string{"tp1q"}, amount: 500, wantErr: common.ErrInsufficientFunds},
+		{name: "fixed fee", utxos: []uint64{1000}, addresses: []string{"tp1q", "tp1q"}, amount: 450, fee: &FeePolicy{Mode: FeeFixed, Amount: 200}, wantErr: common.ErrInsufficientFunds},
+		{name: "estimated fee", utxos: []uint64{1000}, addresses: []string{"tp1q"}, amount: 900, fee: &FeePolicy{Mode: FeeEstimated, MaxFee: 200}, wantErr: common.ErrInsufficientFunds},
+		{name: "more inputs than planned", utxos: []uint64{1000}, maxInputs: 2, addresses: []string{"tp1many"}, amount: 100, wantSpends: []uint64{100}, wantOversized: 1, wantBatchErr: true},
+		{name: "failed chunk", utxos: []uint64{1000, 1000}, maxInputs: 1, maxOutputs: 3, addresses: []string{"tp1q", "tp1q", "tp1bad"}, amount: 100, wantSpends: []uint64{200, 100}, wantTxs: 1, wantBatchErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := NewServerAdapter("testnet", node.URL, "", WithBatchLimits(tt.maxInputs, tt.maxOutputs))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			builds, utxos = nil, nil
+			for _, amount := range tt.utxos {
+				utxos = append(utxos, &internal.UnspentOutput{AssetId: common.BTM, Amount: amount})
+			}
+			for _, amount := range tt.immature {
+				utxos = append(utxos, &internal.UnspentOutput{AssetId: common.BTM, Amount: amount, ValidHeight: 100})
+			}
+
+			var payments []*types.Payment
+			for _, address := range tt.addresses {
+				payments = append(payments, &types.Payment{Address: address, AssetID: common.BTM, Amount: tt.amount})
+			}
+
+			got, err := adapter.BuildBatchTransactionWithOptions("acc", payments, &BuildOptions{Fee: tt.fee})
+			var batchErr *common.BatchError
+			if tt.wantBatchErr {
+				if !errors.As(err, &batchErr) || len(batchErr.Built) != tt.wantTxs {
+					t.Fatalf("BuildBatchTransaction() error = %v, want *common.BatchError", err)
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BuildBatchTransaction() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var oversized int
+			if errors.As(err, &batchErr) {
+				oversized = len(batchErr.Oversized)
+			}
+
+			var gotSpends []uint64
+			for _, actions := range builds {
+				gotSpends = append(gotSpends, actions[0].Amount)
+			}
+			if len(got) != tt.wantTxs || !reflect.DeepEqual(gotSpends, tt.wantSpends) || oversized != tt.wantOversized {
+				t.Errorf("BuildBatchTransaction() txs = %v, spends = %v, oversized = %v, want %v, %v, %v", len(got), gotSpends, oversized, tt.wantTxs, tt.wantSpends, tt.wantOversized)
+			}
+		})
+	}
+}
+
+func TestSelectUTXOs(t *testing.T) {
+	tests := []struct {
+		name       string
+		amounts    []uint64
+		amount     uint64
+		wantPicked []int
+		wantOk     bool
+	}{
+		{name: "largest covers", amounts: []uint64{1000, 10}, amount: 500, wantPicked: []int{0}, wantOk: true},
+		{name: "smaller replace largest", amounts: []uint64{1000, 300, 300}, amount: 500, wantPicked: []int{1, 2}, wantOk: true},
+		{name: "several needed", amounts: []uint64{100, 100, 100, 100}, amount: 300, wantPicked: []int{1, 2, 3}, wantOk: true},
+		{name: "insufficient", amounts: []uint64{100, 100}, amount: 300, wantPicked: []int{0, 1}, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPicked, gotOk := selectUTXOs(tt.amounts, tt.amount)
+			if !reflect.DeepEqual(gotPicked, tt.wantPicked) || gotOk != tt.wantOk {
+				t.Errorf("selectUTXOs() = %v, %v, want %v, %v", gotPicked, gotOk, tt.wantPicked, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_BuildTransactionWithFee(t *testing.T) {
+	var builds []*internal.BuildTransactionReq
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/build-transaction":
+			req := &internal.BuildTransactionReq{}
+			json.NewDecoder(r.Body).Decode(req)
+			builds = append(builds, req)
+			switch {
+			case req.BaseTransaction != nil:
+				w.Write([]byte(`{"status":"success","data":{"raw_transaction":"bb","signing_instructions":[{"position":1}],"fee":1000}}`))
+			case len(builds) > 1:
+				// the UTXOs of the first build are still reserved
+				w.Write([]byte(`{"status":"fail","code":"BTM702","msg":"Available UTXOs of account have been reserved"}`))
+			default:
+				w.Write([]byte(`{"status":"success","data":{"raw_transaction":"aa","signing_instructions":[{"position":0}],"fee":1000}}`))
+			}
+		case "/estimate-transaction-gas":
+			w.Write([]byte(`{"status":"success","data":{"total_neu":1000,"flexible_neu":0,"storage_neu":800,"vm_neu":200}}`))
+		}
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		fee        *FeePolicy
+		wantBuilds int
+		wantFee    uint64
+		wantErr    error
+	}{
+		{name: "node default", fee: nil, wantBuilds: 1},
+		{name: "fixed", fee: &FeePolicy{Mode: FeeFixed, Amount: 5000}, wantBuilds: 1, wantFee: 5000},
+		{name: "estimated", fee: &FeePolicy{Mode: FeeEstimated}, wantBuilds: 2, wantFee: 1000},
+		{name: "estimated with margin", fee: &FeePolicy{Mode: FeeEstimatedWithMargin, MarginPercent: 20}, wantBuilds: 2, wantFee: 1200},
+		{name: "above max", fee: &FeePolicy{Mode: FeeEstimated, MaxFee: 900}, wantBuilds: 1, wantErr: common.ErrFeeTooHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builds = nil
+			got, err := adapter.BuildTransactionWithFee("acc", "tp1q", common.BTM, 100, tt.fee)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BuildTransactionWithFee() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(builds) != tt.wantBuilds {
+				t.Fatalf("BuildTransactionWithFee() builds = %d, want %d", len(builds), tt.wantBuilds)
+			}
+			if err != nil {
+				return
+			}
+
+			last := builds[len(builds)-1]
+			actions := last.Actions
+			if tt.wantBuilds > 1 {
+				if last.BaseTransaction != "aa" || len(actions) != 1 || len(got.SigningInstructions) != 2 {
+					t.Fatalf("BuildTransactionWithFee() fee build = %+v, template = %+v", last, got)
+				}
+				actions = append(builds[0].Actions, actions...)
+			}
+
+			var gotFee uint64
+			if len(actions) == 3 {
+				gotFee = actions[2].Amount
+			}
+			if gotFee != tt.wantFee || got.Fee != 1000 {
+				t.Errorf("BuildTransactionWithFee() fee action = %d, reported = %d, want %d", gotFee, got.Fee, tt.wantFee)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_BuildTransactionWithOptions(t *testing.T) {
+	baseTx := "07010001016401628a9415d9ed4bce588b7bdb0208ccf7ed93cdf96266678eaf7e5b9545340bb362ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f000116001403a7ab809e80f1d26bcae51c05d3ea01d1bdd3b401000201430041ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffbda8d0ffffffff7f0116001483a69a4dfc19f489aa8aa3d33c5493871d41dc5d00013e003cffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80c2d72f01160014d9456c6c541e2ef2ea0b00732176ad1d97b1871400"
+	var body map[string]interface{}
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/get-block-count":
+			w.Write([]byte(`{"status":"success","data":{"block_count":100}}`))
+		case "/build-transaction":
+			body = nil
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"status":"success","data":{"raw_transaction":"00","signing_instructions":[]}}`))
+		}
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     *BuildOptions
+		wantBody map[string]interface{}
+		wantErr  error
+	}{
+		{name: "nil", opts: nil, wantBody: map[string]interface{}{"ttl": 0.0, "time_range": 0.0, "base_transaction": nil}},
+		{name: "all set", opts: &BuildOptions{TTL: time.Minute, TimeRange: 110, BaseTransaction: baseTx}, wantBody: map[string]interface{}{"ttl": 60000.0, "time_range": 110.0, "base_transaction": baseTx}},
+		{name: "negative ttl", opts: &BuildOptions{TTL: -time.Second}, wantErr: common.ErrBadBuildOptions},
+		{name: "sub millisecond ttl", opts: &BuildOptions{TTL: time.Microsecond}, wantErr: common.ErrBadBuildOptions},
+		{name: "ttl too long", opts: &BuildOptions{TTL: MaxBuildTTL + time.Millisecond}, wantErr: common.ErrBadBuildOptions},
+		{name: "time range passed", opts: &BuildOptions{TimeRange: 100}, wantErr: common.ErrBadBuildOptions},
+		{name: "bad base transaction", opts: &BuildOptions{BaseTransaction: "zz"}, wantErr: common.ErrBadBuildOptions},
+		{name: "unknown fee mode", opts: &BuildOptions{Fee: &FeePolicy{Mode: 9}}, wantErr: common.ErrBadBuildOptions},
+		{name: "fixed fee above max", opts: &BuildOptions{Fee: &FeePolicy{Mode: FeeFixed, Amount: 10, MaxFee: 5}}, wantErr: common.ErrBadBuildOptions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body = nil
+			_, err := adapter.BuildTransactionWithOptions("acc", "tp1q", common.BTM, 100, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BuildTransactionWithOptions() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if body != nil {
+					t.Errorf("BuildTransactionWithOptions() built with invalid options")
+				}
+				return
+			}
+
+			for key, want := range tt.wantBody {
+				if !reflect.DeepEqual(body[key], want) {
+					t.Errorf("BuildTransactionWithOptions() %s = %v, want %v", key, body[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestServerAdapter_BuildCrossChainWithdrawal(t *testing.T) {
+	var actions []*internal.Actions
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &internal.BuildTransactionReq{}
+		json.NewDecoder(r.Body).Decode(req)
+		actions = req.Actions
+		w.Write([]byte(`{"status":"success","data":{"raw_transaction":"00","signing_instructions":[]}}`))
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		address     string
+		assetId     string
+		amount      uint64
+		wantAddress string
+		wantProgram string
+		wantErr     error
+	}{
+		{name: "btm to mainchain", address: "tm1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq8e79va", assetId: common.BTM, amount: 100, wantAddress: "tm1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq8e79va"},
+		{name: "eth to ethereum", address: "0x00000000000000000000000000000000000000ff", assetId: common.ETH, amount: 100, wantProgram: "00000000000000000000000000000000000000ff"},
+		{name: "btm to sidechain address", address: "tp1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq9vx3fw", assetId: common.BTM, amount: 100, wantErr: common.ErrInvalidAddress},
+		{name: "btm to ethereum", address: "0x00000000000000000000000000000000000000ff", assetId: common.BTM, amount: 100, wantErr: common.ErrInvalidAddress},
+		{name: "usdt to mainchain", address: "tm1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq8e79va", assetId: common.USDT, amount: 100, wantErr: common.ErrInvalidAddress},
+		{name: "short ethereum address", address: "0x00ff", assetId: common.ETH, amount: 100, wantErr: common.ErrInvalidAddress},
+		{name: "zero amount", address: "tm1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq8e79va", assetId: common.BTM, wantErr: common.ErrBadPayment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions = nil
+			_, err := adapter.BuildCrossChainWithdrawal("acc", tt.address, tt.assetId, tt.amount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BuildCrossChainWithdrawal() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(actions) != 2 || actions[1].Type != "cross_chain_out" || actions[1].Address != tt.wantAddress || actions[1].ControlProgram != tt.wantProgram {
+				t.Errorf("BuildCrossChainWithdrawal() actions = %+v", actions)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_BuildVote(t *testing.T) {
+	nodePubkey := "1c0c2c75073c438b5612005bacdcbde2352277c44a22c5a31aa35899a3369e5fe61bb70eee5c0de48bcefddca59b14162e411b5f11d1966661a25491d48fcdbf"
+	var actions []*internal.Actions
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/create-account-receiver":
+			w.Write([]byte(`{"status":"success","data":{"address":"tp1q3xjrt7ahef583lckefvvhg3djngq0l3rllkkr9"}}`))
+		case "/build-transaction":
+			req := &internal.BuildTransactionReq{}
+			json.NewDecoder(r.Body).Decode(req)
+			actions = req.Actions
+			w.Write([]byte(`{"status":"success","data":{"raw_transaction":"00","signing_instructions":[]}}`))
+		}
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		build      func(accountId, nodePubkey string, amount uint64) (*internal.BuildTransactionResp, error)
+		nodePubkey string
+		amount     uint64
+		wantTypes  []string
+		wantErr    error
+	}{
+		{name: "vote", build: adapter.BuildVote, nodePubkey: nodePubkey, amount: 100000000, wantTypes: []string{"spend_account", "vote_output"}},
+		{name: "vote below minimum", build: adapter.BuildVote, nodePubkey: nodePubkey, amount: 99999999, wantErr: common.ErrBadVoteAmount},
+		{name: "vote bad pubkey", build: adapter.BuildVote, nodePubkey: "1c0c", amount: 100000000, wantErr: common.ErrBadLenXPubStr},
+		{name: "veto", build: adapter.BuildVeto, nodePubkey: nodePubkey, amount: 1, wantTypes: []string{"veto", "control_address"}},
+		{name: "veto zero", build: adapter.BuildVeto, nodePubkey: nodePubkey, wantErr: common.ErrBadPayment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions = nil
+			_, err := tt.build("acc", tt.nodePubkey, tt.amount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("build error = %v, want %v", err, tt.wantErr)
+			}
+
+			var gotTypes []string
+			for _, action := range actions {
+				gotTypes = append(gotTypes, action.Type)
+			}
+			if !reflect.DeepEqual(gotTypes, tt.wantTypes) {
+				t.Errorf("build action types = %v, want %v", gotTypes, tt.wantTypes)
+			}
+			if err == nil && actions[0].Vote+actions[1].Vote != tt.nodePubkey {
+				t.Errorf("build vote = %v, want %v", actions, tt.nodePubkey)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_ListAccountVotes(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","data":[
+			{"account_id":"acc","total_vote_number":300,"vote_details":[{"vote":"aa","vote_number":100},{"vote":"bb","vote_number":200}]},
+			{"account_id":"other","total_vote_number":50,"vote_details":[{"vote":"aa","vote_number":50}]}]}`))
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := adapter.ListAccountVotes("acc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*types.Vote{{NodePubkey: "aa", Amount: 100}, {NodePubkey: "bb", Amount: 200}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListAccountVotes() got = %v, want %v", got, want)
+	}
+}
+
+func TestServerAdapter_TxDetails(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/get-block-count":
+			w.Write([]byte(`{"status":"success","data":{"block_count":104}}`))
+		case "/get-transaction":
+			w.Write([]byte(`{"status":"success","data":{"tx_id":"11ca","block_time":1,"block_hash":"bb","block_height":100,"block_index":2,"status_fail":true,"size":330,
+				"inputs":[{"type":"spend","address":"tp1q","amount":500,"asset_id":"` + common.BTM + `","spent_output_id":"5a5a","control_program":"0014aa"},{"type":"spend","address":"tp1q","amount":7,"asset_id":"` + common.ETH + `"}],
+				"outputs":[{"type":"control","id":"6b6b","address":"tp1q","amount":300,"asset_id":"` + common.BTM + `","control_program":"0014bb"},{"type":"control","address":"tp1q","amount":7,"asset_id":"` + common.ETH + `"}]}}`))
+		case "/get-block":
+			w.Write([]byte(`{"status":"success","data":{"hash":"cc","height":103,"timestamp":9,"transactions":[{"id":"aa","size":100,"inputs":[],"outputs":[]},{"id":"ab","size":200,"inputs":[],"outputs":[]}]}}`))
+		case "/list-unconfirmed-transactions":
+			w.Write([]byte(`{"status":"success","data":{"tx_ids":["dd"]}}`))
+		case "/get-unconfirmed-transaction":
+			w.Write([]byte(`{"status":"success","data":{"id":"dd","size":50,"inputs":[],"outputs":[]}}`))
+		}
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := adapter.GetTransaction("11ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.BlockHeight != 100 || tx.BlockHash != "bb" || tx.Position != 2 || !tx.StatusFail || tx.Size != 330 || tx.Confirmations != 5 {
+		t.Errorf("GetTransaction() = %+v", tx)
+	}
+	if want := map[string]uint64{common.BTM: 200}; !reflect.DeepEqual(tx.Fee, want) {
+		t.Errorf("GetTransaction() fee = %v, want %v", tx.Fee, want)
+	}
+	if input := tx.Inputs[0]; input.Type != common.UTXOSpend || input.SpentOutputID != "5a5a" || input.Index != 0 || input.ControlProgram != "0014aa" {
+		t.Errorf("GetTransaction() input = %+v", input)
+	}
+	if output := tx.Outputs[1]; output.Type != common.UTXOControl || output.OutputID != "" || output.Index != 1 {
+		t.Errorf("GetTransaction() output = %+v", output)
+	}
+	if output := tx.Outputs[0]; output.OutputID != "6b6b" || output.ControlProgram != "0014bb" {
+		t.Errorf("GetTransaction() output = %+v", output)
+	}
+
+	txs, err := adapter.GetBlockTxs(103)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 2 || txs[1].BlockHash != "cc" || txs[1].BlockHeight != 103 || txs[1].Position != 1 || txs[1].Size != 200 || txs[1].Confirmations != 2 {
+		t.Errorf("GetBlockTxs() = %+v", txs)
+	}
+
+	txs, err = adapter.GetRawMemPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].TxHash != "dd" || txs[0].Size != 50 || txs[0].Confirmations != 0 {
+		t.Errorf("GetRawMemPool() = %+v", txs)
+	}
+}
+
+// fakeChain serves the block count and blocks of a chain whose best chain can
+// be replaced to simulate reorganizations.
+type fakeChain struct {
+	blocks  map[string]*internal.GetBlockResp
+	best    []string
+	mempool []*internal.Transaction
+	// left are listed in the mempool but gone by the time they are fetched
+	left []string
+	// calls counts the requests per path
+	calls map[string]int
+}
+
+func newFakeChain(hashes ...string) *fakeChain {
+	c := &fakeChain{blocks: map[string]*internal.GetBlockResp{}, calls: map[string]int{}}
+	c.set(hashes...)
+	return c
+}
+
+func (c *fakeChain) set(hashes ...string) {
+	c.best = hashes
+	for i, hash := range hashes {
+		if _, ok := c.blocks[hash]; !ok {
+			prev := ""
+			if i > 0 {
+				prev = hashes[i-1]
+			}
+			c.blocks[hash] = &internal.GetBlockResp{Hash: hash, Height: uint64(i), PreviousBlockHash: prev}
+		}
+	}
+}
+
+func (c *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.calls[r.URL.Path]++
+	var data interface{}
+	switch r.URL.Path {
+	case "/get-block-count":
+		data = map[string]uint64{"block_count": uint64(len(c.best) - 1)}
+	case "/get-block":
+		req := &internal.GetBlockReq{}
+		json.NewDecoder(r.Body).Decode(req)
+		if req.BlockHash != "" {
+			data = c.blocks[req.BlockHash]
+		} else {
+			data = c.blocks[c.best[req.BlockHeight]]
+		}
+	case "/list-unconfirmed-transactions":
+		resp := &internal.ListUnconfirmedTxResp{TxIds: append([]string{}, c.left...)}
+		for _, tx := range c.mempool {
+			resp.TxIds = append(resp.TxIds, tx.ID)
+		}
+		data = resp
+	case "/get-unconfirmed-transaction":
+		req := &internal.GetUnconfirmedTxReq{}
+		json.NewDecoder(r.Body).Decode(req)
+		for _, id := range c.left {
+			if id == req.TxId {
+				w.Write([]byte(`{"status":"fail","code":"BTM000","msg":"Bytom API Error","error_detail":"transaction are not existed in the mempool"}`))
+				return
+			}
+		}
+		for _, tx := range c.mempool {
+			if tx.ID == req.TxId {
+				data = tx
+			}
+		}
+	}
+	resp, _ := json.Marshal(data)
+	w.Write([]byte(`{"status":"success","data":` + string(resp) + `}`))
+}
+
+func TestBlockScanner(t *testing.T) {
+	chain := newFakeChain("g")
+	node := httptest.NewServer(chain)
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	handler := func(ctx context.Context, event *ScanEvent) error {
+		sign := "+"
+		if event.Type == BlockDisconnected {
+			sign = "-"
+		}
+		events = append(events, sign+event.Block.Hash)
+		return nil
+	}
+
+	tests := []struct {
+		name           string
+		chain          []string
+		wantEvents     []string
+		wantCheckpoint Checkpoint
+		wantErr        error
+		// wantCalls are the requests per path when set
+		wantCalls map[string]int
+	}{
+		{name: "initial", chain: []string{"g", "a1", "a2", "a3"}, wantEvents: []string{"+a1", "+a2", "+a3"}, wantCheckpoint: Checkpoint{Height: 3, Hash: "a3"}, wantCalls: map[string]int{"/get-block-count": 4, "/get-block": 4}},
+		{name: "no new block", chain: []string{"g", "a1", "a2", "a3"}, wantCheckpoint: Checkpoint{Height: 3, Hash: "a3"}, wantCalls: map[string]int{"/get-block-count": 1, "/get-block": 1}},
+		{name: "reorg", chain: []string{"g", "a1", "b2", "b3", "b4"}, wantEvents: []string{"-a3", "-a2", "+b2", "+b3", "+b4"}, wantCheckpoint: Checkpoint{Height: 4, Hash: "b4"}},
+		{name: "shorter chain", chain: []string{"g", "a1", "c2"}, wantEvents: []string{"-b4", "-b3", "-b2", "+c2"}, wantCheckpoint: Checkpoint{Height: 2, Hash: "c2"}},
+		{name: "same height", chain: []string{"g", "a1", "e2"}, wantEvents: []string{"-c2", "+e2"}, wantCheckpoint: Checkpoint{Height: 2, Hash: "e2"}},
+		{name: "grow", chain: []string{"g", "a1", "e2", "c3", "c4", "c5"}, wantEvents: []string{"+c3", "+c4", "+c5"}, wantCheckpoint: Checkpoint{Height: 5, Hash: "c5"}},
+		{name: "too deep", chain: []string{"g", "a1", "d2", "d3", "d4", "d5", "d6"}, wantEvents: []string{"-c5", "-c4", "-c3"}, wantCheckpoint: Checkpoint{Height: 2, Hash: "e2"}, wantErr: common.ErrReorgTooDeep},
+	}
+	scanner := NewBlockScanner(adapter, Checkpoint{}, WithMaxReorgDepth(3))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events = nil
+			chain.calls = map[string]int{}
+			chain.set(tt.chain...)
+			if err := scanner.Scan(context.Background(), handler); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Scan() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(events, tt.wantEvents) {
+				t.Errorf("Scan() events = %v, want %v", events, tt.wantEvents)
+			}
+			if scanner.Checkpoint() != tt.wantCheckpoint {
+				t.Errorf("Scan() checkpoint = %v, want %v", scanner.Checkpoint(), tt.wantCheckpoint)
+			}
+			if tt.wantCalls != nil && !reflect.DeepEqual(chain.calls, tt.wantCalls) {
+				t.Errorf("Scan() calls = %v, want %v", chain.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestBlockScanner_CheckpointStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chain := newFakeChain("g", "a1", "a2")
+	node := httptest.NewServer(chain)
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		store CheckpointStore
+	}{
+		{name: "memory", store: NewMemoryCheckpointStore()},
+		{name: "file", store: NewFileCheckpointStore(filepath.Join(dir, "checkpoint.json"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain.set("g", "a1", "a2")
+			if got, err := tt.store.Load(); got != nil || err != nil {
+				t.Fatalf("Load() = %v, %v, want nothing stored", got, err)
+			}
+
+			var events []string
+			handler := func(ctx context.Context, event *ScanEvent) error {
+				if event.Block.Hash == "b3" {
+					return errors.New("handler failed")
+				}
+				events = append(events, event.Block.Hash)
+				return nil
+			}
+
+			if err := NewBlockScanner(adapter, Checkpoint{}, WithCheckpointStore(tt.store)).Scan(context.Background(), handler); err != nil {
+				t.Fatal(err)
+			}
+
+			// a restarted scanner resumes at the stored checkpoint, and a failed
+			// handler leaves the checkpoint before its event
+			chain.set("g", "a1", "a2", "a3", "b3")
+			if err := NewBlockScanner(adapter, Checkpoint{}, WithCheckpointStore(tt.store)).Scan(context.Background(), handler); err == nil {
+				t.Fatal("Scan() error = nil, want handler error")
+			}
+
+			if want := []string{"a1", "a2", "a3"}; !reflect.DeepEqual(events, want) {
+				t.Errorf("Scan() events = %v, want %v", events, want)
+			}
+			if got, err := tt.store.Load(); err != nil || *got != (Checkpoint{Height: 3, Hash: "a3"}) {
+				t.Errorf("Load() = %v, %v, want a3", got, err)
+			}
+		})
+	}
+}
+
+// depositTx is a transaction paying amount of BTM to address in output id.
+func depositTx(t *testing.T, txId, id, address string) *internal.Transaction {
+	tx := &internal.Transaction{}
+	data := `{"id":"` + txId + `","outputs":[{"type":"control","id":"` + id + `","address":"` + address + `","amount":100,"asset_id":"` + common.BTM + `"}]}`
+	if err := json.Unmarshal([]byte(data), tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestDepositWatcher(t *testing.T) {
+	chain := newFakeChain("g")
+	node := httptest.NewServer(chain)
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t1 := depositTx(t, "t1", "o1", "tp1w")
+	t2 := depositTx(t, "t2", "o2", "tp1w")
+	t3 := depositTx(t, "t3", "o3", "tp1w")
+	other := depositTx(t, "t9", "o9", "tp1other")
+	chain.set("g", "b1", "b2", "b3")
+	chain.set("g", "b1", "b2", "c3")
+	chain.blocks["b1"].Txs = []*internal.Transaction{t1, other}
+	chain.blocks["b3"].Txs = []*internal.Transaction{t3}
+
+	tests := []struct {
+		name       string
+		chain      []string
+		mempool    []*internal.Transaction
+		left       []string
+		wantEvents []string
+	}{
+		{name: "mempool", chain: []string{"g"}, mempool: []*internal.Transaction{t1, other}, left: []string{"t8"}, wantEvents: []string{"seen o1 0"}},
+		{name: "packed", chain: []string{"g", "b1"}, wantEvents: []string{"confirmed o1 1"}},
+		{name: "final", chain: []string{"g", "b1", "b2"}, wantEvents: []string{"final o1 2"}},
+		{name: "mempool and block", chain: []string{"g", "b1", "b2", "b3"}, mempool: []*internal.Transaction{t2}, wantEvents: []string{"seen o3 0", "seen o2 0", "confirmed o3 1"}},
+		{name: "reverted", chain: []string{"g", "b1", "b2", "c3"}, wantEvents: []string{"reverted o3 1", "reverted o2 0"}},
+	}
+
+	names := map[DepositEventType]string{DepositSeen: "seen", DepositConfirmed: "confirmed", DepositFinal: "final", DepositReverted: "reverted"}
+	watcher := NewDepositWatcher(adapter, Checkpoint{}, []string{"tp1w"}, WithConfirmations(common.BTM, 2))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain.best, chain.mempool, chain.left = tt.chain, tt.mempool, tt.left
+			if err := watcher.Poll(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			var events []string
+			for len(watcher.Events()) > 0 {
+				event := <-watcher.Events()
+				events = append(events, names[event.Type]+" "+event.Output.OutputID+" "+strconv.FormatUint(event.Confirmations, 10))
+			}
+			if !reflect.DeepEqual(events, tt.wantEvents) {
+				t.Errorf("Poll() events = %v, want %v", events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestAccountDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "directory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stores := map[string]func() DirectoryStore{
+		"memory": func() DirectoryStore { return NewMemoryDirectoryStore() },
+		"file":   func() DirectoryStore { return NewFileDirectoryStore(filepath.Join(dir, "directory.json")) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			directory, err := NewAccountDirectory(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := directory.AddAccount("acc", "alice", []string{"xpub"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := directory.AddAddress("acc", "tp1a"); err != nil {
+				t.Fatal(err)
+			}
+			if err := directory.AddAddress("acc", "tp1a"); err != nil {
+				t.Errorf("AddAddress() again error = %v", err)
+			}
+			if err := directory.AddAddress("other", "tp1a"); !errors.Is(err, common.ErrAddressTaken) {
+				t.Errorf("AddAddress() taken error = %v, want %v", err, common.ErrAddressTaken)
+			}
+			if err := directory.AddAddress("other", "tp1b"); err != nil {
+				t.Fatal(err)
+			}
+
+			reloaded, err := NewAccountDirectory(store)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if accountId, ok := reloaded.AccountForAddress("tp1a"); !ok || accountId != "acc" {
+				t.Errorf("AccountForAddress() = %v, %v, want acc", accountId, ok)
+			}
+			if accountId, ok := reloaded.AccountForXPub("xpub"); !ok || accountId != "acc" {
+				t.Errorf("AccountForXPub() = %v, %v, want acc", accountId, ok)
+			}
+			if got := reloaded.Addresses("other"); !reflect.DeepEqual(got, []string{"tp1b"}) {
+				t.Errorf("Addresses() = %v, want [tp1b]", got)
+			}
+			if record, ok := reloaded.Account("acc"); !ok || record.Alias != "alice" {
+				t.Errorf("Account() = %+v, %v", record, ok)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_AccountDirectory(t *testing.T) {
+	xpub := "1c0c2c75073c438b5612005bacdcbde2352277c44a22c5a31aa35899a3369e5fe61bb70eee5c0de48bcefddca59b14162e411b5f11d1966661a25491d48fcdbf"
+	var txsReq *internal.ListTxReq
+	var walletTxs []*internal.Transaction
+	if err := json.Unmarshal([]byte(`[
+		{"tx_id":"t1","inputs":[],"outputs":[{"address":"tp1r","amount":100,"asset_id":"`+common.BTM+`"}]},
+		{"tx_id":"t2","inputs":[],"outputs":[{"address":"tp1other","amount":900,"asset_id":"`+common.BTM+`"}]},
+		{"tx_id":"t3","inputs":[{"address":"tp1r","amount":100,"asset_id":"`+common.BTM+`"}],"outputs":[]}]`), &walletTxs); err != nil {
+		t.Fatal(err)
+	}
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/create-account":
+			w.Write([]byte(`{"status":"success","data":{"id":"acc","alias":"alice"}}`))
+		case "/create-account-receiver":
+			w.Write([]byte(`{"status":"success","data":{"address":"tp1r","control_program":"0014aa"}}`))
+		case "/list-balances":
+			w.Write([]byte(`{"status":"success","data":[{"asset_id":"` + common.BTM + `","amount":1000}]}`))
+		case "/list-unspent-outputs":
+			w.Write([]byte(`{"status":"success","data":[
+				{"id":"o1","account_id":"acc","address":"tp1r","asset_id":"` + common.BTM + `","amount":100},
+				{"id":"o2","account_id":"acc","address":"tp1other","asset_id":"` + common.BTM + `","amount":900},
+				{"id":"o3","account_id":"acc","address":"tp1r","asset_id":"` + common.BTM + `","amount":20}]}`))
+		case "/list-transactions":
+			txsReq = &internal.ListTxReq{}
+			json.NewDecoder(r.Body).Decode(txsReq)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": listTxsPage(walletTxs, txsReq)})
+		case "/get-block-count":
+			w.Write([]byte(`{"status":"success","data":{"block_count":10}}`))
+		}
+	}))
+	defer node.Close()
+
+	directory, err := NewAccountDirectory(NewMemoryDirectoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "", WithAccountDirectory(directory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := adapter.CreateAccount(xpub, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adapter.PubkeyToAddress(xpub); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adapter.CreateAddress("acc"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"tp1q3xjrt7ahef583lckefvvhg3djngq0l3rllkkr9", "tp1r"}
+	if got := directory.Addresses("acc"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Addresses() = %v, want %v", got, want)
+	}
+
+	balanceTests := []struct {
+		name      string
+		accountId string
+		want      uint64
+	}{
+		{name: "account", accountId: "acc", want: 1000},
+		{name: "address", accountId: "tp1r", want: 120},
+	}
+	for _, tt := range balanceTests {
+		t.Run("balances "+tt.name, func(t *testing.T) {
+			got, err := adapter.BalancesForAddress(tt.accountId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].Balance != tt.want {
+				t.Errorf("BalancesForAddress() = %+v, want %d", got, tt.want)
+			}
+		})
+	}
+
+	txsTests := []struct {
+		name      string
+		accountId string
+		start     int
+		limit     int
+		want      []string
+		wantReq   internal.ListTxReq
+	}{
+		{name: "account", accountId: "acc", start: 1, limit: 2, want: []string{"t1", "t2"}, wantReq: internal.ListTxReq{AccountId: "acc", Detail: true, From: 1, Count: 2}},
+		{name: "address", accountId: "tp1r", want: []string{"t1", "t3"}, wantReq: internal.ListTxReq{AccountId: "acc", Detail: true, Count: txPageSize}},
+		{name: "address page", accountId: "tp1r", start: 1, limit: 1, want: []string{"t3"}, wantReq: internal.ListTxReq{AccountId: "acc", Detail: true, Count: txPageSize}},
+	}
+	for _, tt := range txsTests {
+		t.Run("txs "+tt.name, func(t *testing.T) {
+			txs, err := adapter.TxsForAddress(tt.accountId, tt.start, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, tx := range txs {
+				got = append(got, tx.TxHash)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TxsForAddress() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(*txsReq, tt.wantReq) {
+				t.Errorf("TxsForAddress() request = %+v, want %+v", *txsReq, tt.wantReq)
+			}
+		})
+	}
+}
+
+// listTxsPage pages txs, newest first, like list-transactions of vapord
+// v1.1.0: from is ignored, a zero count returns nothing and a page starts
+// after start_tx_id.
+func listTxsPage(txs []*internal.Transaction, req *internal.ListTxReq) []*internal.Transaction {
+	i := 0
+	if req.StartTxId != "" {
+		for i < len(txs) && txs[i].TxId != req.StartTxId {
+			i++
+		}
+		i++
+	}
+
+	page := []*internal.Transaction{}
+	for ; i < len(txs) && len(page) < req.Count; i++ {
+		page = append(page, txs[i])
+	}
+	return page
+}
+
+func TestServerAdapter_TxsForAddressPaging(t *testing.T) {
+	// every third of 250 transactions pays to the recorded address
+	var txsJSON []string
+	for i := 0; i < 250; i++ {
+		address := "tp1other"
+		if i%3 == 0 {
+			address = "tp1r"
+		}
+		txsJSON = append(txsJSON, `{"tx_id":"t`+strconv.Itoa(i)+`","inputs":[],"outputs":[{"address":"`+address+`","amount":1,"asset_id":"`+common.BTM+`"}]}`)
+	}
+	var walletTxs []*internal.Transaction
+	if err := json.Unmarshal([]byte("["+strings.Join(txsJSON, ",")+"]"), &walletTxs); err != nil {
+		t.Fatal(err)
+	}
+
+	var pages int
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &internal.ListTxReq{}
+		json.NewDecoder(r.Body).Decode(req)
+		pages++
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": listTxsPage(walletTxs, req)})
+	}))
+	defer node.Close()
+
+	directory, err := NewAccountDirectory(NewMemoryDirectoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := directory.AddAddress("acc", "tp1r"); err != nil {
+		t.Fatal(err)
+	}
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "", WithAccountDirectory(directory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		start     int
+		limit     int
+		wantCount int
+		wantFirst string
+	}{
+		{name: "all", wantCount: 84, wantFirst: "t0"},
+		{name: "page", start: 40, limit: 10, wantCount: 10, wantFirst: "t120"},
+		{name: "last page", start: 80, limit: 10, wantCount: 4, wantFirst: "t240"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages = 0
+			txs, err := adapter.TxsForAddress("tp1r", tt.start, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(txs) != tt.wantCount || txs[0].TxHash != tt.wantFirst {
+				t.Errorf("TxsForAddress() = %d transactions from %s, want %d from %s", len(txs), txs[0].TxHash, tt.wantCount, tt.wantFirst)
+			}
+			if pages != 3 {
+				t.Errorf("list-transactions pages = %d, want 3", pages)
+			}
+		})
+	}
+}
+
+func TestServerAdapter_CreateAccountIdempotent(t *testing.T) {
+	xpub := "1c0c2c75073c438b5612005bacdcbde2352277c44a22c5a31aa35899a3369e5fe61bb70eee5c0de48bcefddca59b14162e411b5f11d1966661a25491d48fcdbf"
+	otherXPub := strings.Repeat("ab", 64)
+	multisig := []string{xpub, chainkd.RootXPrv([]byte("vapor-adapter other seed")).XPub().String()}
+	accounts := make(map[string]*internal.Account)
+	var listed bool
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/create-account":
+			req := &internal.CreateAccountReq{}
+			json.NewDecoder(r.Body).Decode(req)
+			if _, ok := accounts[req.Alias]; ok {
+				w.Write([]byte(`{"status":"fail","code":"BTM000","msg":"Bytom API Error","error_detail":"Duplicate account alias"}`))
+				return
+			}
+
+			accounts[req.Alias] = &internal.Account{ID: "acc" + strconv.Itoa(len(accounts)), Alias: req.Alias, XPubs: req.RootXpubs, Quorum: req.Quorum}
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": map[string]string{"id": accounts[req.Alias].ID, "alias": req.Alias}})
+		case "/list-accounts":
+			req := &internal.ListAccountsReq{}
+			json.NewDecoder(r.Body).Decode(req)
+			listed = true
+			resp := []*internal.Account{}
+			for alias, account := range accounts {
+				if req.Alias == "" || req.Alias == alias {
+					resp = append(resp, account)
+				}
+			}
+			sort.Slice(resp, func(i, j int) bool { return resp[i].ID < resp[j].ID })
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": resp})
+		}
+	}))
+	defer node.Close()
+
+	adapter, err := NewServerAdapter("testnet", node.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reuse, err := NewServerAdapter("testnet", node.URL, "", WithXPubAccountReuse())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		create     func() (string, error)
+		want       string
+		wantErr    error
+		wantListed bool
+	}{
+		{name: "new", create: func() (string, error) { return adapter.CreateAccount(xpub, "alice") }, want: "acc0"},
+		{name: "same xpub", create: func() (string, error) { return adapter.CreateAccount(xpub, "alice") }, want: "acc0", wantListed: true},
+		{name: "same xpub other alias", create: func() (string, error) { return adapter.CreateAccount(xpub, "bob") }, want: "acc1"},
+		{name: "reuse xpub account", create: func() (string, error) { return reuse.CreateAccount(xpub, "carol") }, want: "acc0", wantListed: true},
+		{name: "other xpub", create: func() (string, error) { return adapter.CreateAccount(otherXPub, "alice") }, wantErr: common.ErrDuplicateAlias, wantListed: true},
+		{name: "prefix", create: func() (string, error) { return adapter.CreateAccountWithPrefix(otherXPub, "user") }, want: "acc2"},
+		{name: "prefix again", create: func() (string, error) { return adapter.CreateAccountWithPrefix(otherXPub, "user") }, want: "acc2", wantListed: true},
+		{name: "multisig", create: func() (string, error) { return adapter.CreateMultisigAccount(multisig, 2, "vault") }, want: "acc3"},
+		{name: "multisig again", create: func() (string, error) { return adapter.CreateMultisigAccount(multisig, 2, "vault") }, want: "acc3", wantListed: true},
+		{name: "multisig quorum", create: func() (string, error) { return adapter.CreateMultisigAccount(multisig, 1, "vault") }, wantErr: common.ErrDuplicateAlias, wantListed: true},
+		{name: "bad quorum", create: func() (string, error) { return adapter.CreateMultisigAccount(multisig, 3, "safe") }, wantErr: common.ErrBadQuorum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed = false
+			got, err := tt.create()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateAccount() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CreateAccount() = %v, want %v", got, tt.want)
+			}
+			if listed != tt.wantListed {
+				t.Errorf("CreateAccount() listed accounts = %v, want %v", listed, tt.wantListed)
+			}
+		})
+	}
+
+	if alias := AccountAlias("User", otherXPub); alias != AccountAlias("user", strings.ToUpper(otherXPub)) || !strings.HasPrefix(alias, "user_") || alias == AccountAlias("user", xpub) {
+		t.Errorf("AccountAlias() = %v", alias)
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/bytom/vapor/crypto/ed25519/chainkd"
+	vaporTypes "github.com/bytom/vapor/protocol/bc/types"
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+)
+
+// SignTransaction signs the transaction of tpl offline with the given root
+// private keys and returns the signed raw transaction hex. Each raw_tx_signature
+// component is signed by the key whose xpub it lists, derived along the
+// component's derivation path. The signatures are also written back into tpl,
+// so a multi-signature transaction can be passed on to the next signer, in
+// which case common.ErrNotEnoughSigs is returned until the quorum is reached.
+func (c *ClientAdapter) SignTransaction(tpl *internal.BuildTransactionResp, xPrvs ...chainkd.XPrv) (string, error) {
+	tx := &vaporTypes.Tx{}
+	if err := tx.UnmarshalText([]byte(tpl.RawTransaction)); err != nil {
+		return "", errors.Wrap(err, "unmarshal raw transaction")
+	}
+
+	keys := make(map[string]chainkd.XPrv)
+	for _, xPrv := range xPrvs {
+		keys[xPrv.XPub().String()] = xPrv
+	}
+
+	var missingSigs bool
+	for _, inst := range tpl.SigningInstructions {
+		if inst.Position < 0 || inst.Position >= len(tx.Inputs) {
+			return "", errors.Wrapf(common.ErrBadSigningInst, "position %d", inst.Position)
+		}
+
+		sigHash := tx.SigHash(uint32(inst.Position)).Byte32()
+		var arguments [][]byte
+		for _, component := range inst.WitnessComponents {
+			switch component.Type {
+			case "raw_tx_signature":
+				sigs, err := signWitnessComponent(component, keys, sigHash[:])
+				if err != nil {
+					return "", errors.Wrapf(err, "sign input %d", inst.Position)
+				}
+
+				if len(sigs) < component.Quorum {
+					missingSigs = true
+				}
+				arguments = append(arguments, sigs...)
+			case "data":
+				value, err := hex.DecodeString(component.Value)
+				if err != nil {
+					return "", errors.Wrapf(err, "decode data witness of input %d", inst.Position)
+				}
+				arguments = append(arguments, value)
+			default:
+				return "", errors.Wrapf(common.ErrUnsupportedWitness, "%s of input %d", component.Type, inst.Position)
+			}
+		}
+		tx.SetInputArguments(uint32(inst.Position), arguments)
+	}
+
+	if missingSigs {
+		return "", common.ErrNotEnoughSigs
+	}
+
+	rawTx, err := tx.MarshalText()
+	if err != nil {
+		return "", errors.Wrap(err, "marshal signed transaction")
+	}
+	return string(rawTx), nil
+}
+
+// signWitnessComponent fills the missing signatures of component with the keys
+// it holds and returns at most quorum signatures, in key order.
+func signWitnessComponent(component *internal.WitnessComponent, keys map[string]chainkd.XPrv, sigHash []byte) ([][]byte, error) {
+	if len(component.Signatures) < len(component.Keys) {
+		sigs := make([]string, len(component.Keys))
+		copy(sigs, component.Signatures)
+		component.Signatures = sigs
+	}
+
+	for i, key := range component.Keys {
+		xPrv, ok := keys[key.Xpub]
+		if !ok || component.Signatures[i] != "" {
+			continue
+		}
+
+		path, err := decodeDerivationPath(key.DerivationPath)
+		if err != nil {
+			return nil, err
+		}
+		component.Signatures[i] = hex.EncodeToString(xPrv.Derive(path).Sign(sigHash))
+	}
+
+	var sigs [][]byte
+	for _, sig := range component.Signatures {
+		if sig == "" || len(sigs) >= component.Quorum {
+			continue
+		}
+
+		sigBytes, err := hex.DecodeString(sig)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode signature")
+		}
+		sigs = append(sigs, sigBytes)
+	}
+	return sigs, nil
+}
+
+func decodeDerivationPath(path []string) ([][]byte, error) {
+	var result [][]byte
+	for _, p := range path {
+		b, err := hex.DecodeString(p)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("decode derivation path: %s", p))
+		}
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+func encodeDerivationPath(path [][]byte) []string {
+	var result []string
+	for _, p := range path {
+		result = append(result, hex.EncodeToString(p))
+	}
+	return result
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"vapor-adapter/common"
+	"vapor-adapter/internal"
+	"vapor-adapter/types"
+)
+
+func (s *ServerAdapter) BuildVote(accountId, nodePubkey string, amount uint64) (*internal.BuildTransactionResp, error) {
+	return s.BuildVoteWithContext(context.Background(), accountId, nodePubkey, amount)
+}
+
+func (s *ServerAdapter) BuildVoteWithContext(ctx context.Context, accountId, nodePubkey string, amount uint64) (*internal.BuildTransactionResp, error) {
+	return s.BuildVoteWithOptionsContext(ctx, accountId, nodePubkey, amount, nil)
+}
+
+func (s *ServerAdapter) BuildVoteWithOptions(accountId, nodePubkey string, amount uint64, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	return s.BuildVoteWithOptionsContext(context.Background(), accountId, nodePubkey, amount, opts)
+}
+
+// BuildVoteWithOptionsContext builds the unsigned transaction voting amount of
+// BTM from accountId for the consensus node nodePubkey. The vote output is paid
+// to a new address of the account, so a later veto returns the BTM to it.
+func (s *ServerAdapter) BuildVoteWithOptionsContext(ctx context.Context, accountId, nodePubkey string, amount uint64, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	if _, err := pubkeyToXPub(nodePubkey); err != nil {
+		return nil, errors.Wrap(err, "node pubkey")
+	}
+
+	if amount < s.netParams.MinVoteOutputAmount {
+		return nil, errors.Wrapf(common.ErrBadVoteAmount, "%d below %d", amount, s.netParams.MinVoteOutputAmount)
+	}
+
+	if err := s.checkBuildOptions(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	receiver, err := s.createReceiver(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []*internal.Actions{
+		{AccountId: accountId, Amount: amount, AssetId: common.BTM, Type: "spend_account"},
+		{Amount: amount, AssetId: common.BTM, Type: "vote_output", Address: receiver.Address, Vote: nodePubkey},
+	}
+	return s.buildWithOptions(ctx, accountId, actions, opts)
+}
+
+func (s *ServerAdapter) BuildVeto(accountId, nodePubkey string, amount uint64) (*internal.BuildTransactionResp, error) {
+	return s.BuildVetoWithContext(context.Background(), accountId, nodePubkey, amount)
+}
+
+func (s *ServerAdapter) BuildVetoWithContext(ctx context.Context, accountId, nodePubkey string, amount uint64) (*internal.BuildTransactionResp, error) {
+	return s.BuildVetoWithOptionsContext(ctx, accountId, nodePubkey, amount, nil)
+}
+
+func (s *ServerAdapter) BuildVetoWithOptions(accountId, nodePubkey string, amount uint64, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	return s.BuildVetoWithOptionsContext(context.Background(), accountId, nodePubkey, amount, opts)
+}
+
+// BuildVetoWithOptionsContext builds the unsigned transaction withdrawing
+// amount of the BTM accountId votes for the consensus node nodePubkey back to
+// a new address of the account.
+func (s *ServerAdapter) BuildVetoWithOptionsContext(ctx context.Context, accountId, nodePubkey string, amount uint64, opts *BuildOptions) (*internal.BuildTransactionResp, error) {
+	if _, err := pubkeyToXPub(nodePubkey); err != nil {
+		return nil, errors.Wrap(err, "node pubkey")
+	}
+
+	if amount == 0 {
+		return nil, errors.Wrap(common.ErrBadPayment, "veto amount is zero")
+	}
+
+	if err := s.checkBuildOptions(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	receiver, err := s.createReceiver(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []*internal.Actions{
+		{AccountId: accountId, Amount: amount, AssetId: common.BTM, Type: "veto", Vote: nodePubkey},
+		{Amount: amount, AssetId: common.BTM, Type: "control_address", Address: receiver.Address},
+	}
+	return s.buildWithOptions(ctx, accountId, actions, opts)
+}
+
+func (s *ServerAdapter) ListAccountVotes(accountId string) ([]*types.Vote, error) {
+	return s.ListAccountVotesWithContext(context.Background(), accountId)
+}
+
+// ListAccountVotesWithContext returns the BTM accountId currently votes for
+// each consensus node.
+func (s *ServerAdapter) ListAccountVotesWithContext(ctx context.Context, accountId string) ([]*types.Vote, error) {
+	var resp []*internal.AccountVotes
+	if err := s.call(ctx, "/list-account-votes", &internal.AccountReq{AccountId: accountId}, &resp, true); err != nil {
+		return nil, errors.Wrapf(err, "request list account votes")
+	}
+
+	var votes []*types.Vote
+	for _, accountVotes := range resp {
+		if accountVotes.AccountId != accountId {
+			continue
+		}
+
+		for _, detail := range accountVotes.VoteDetails {
+			votes = append(votes, &types.Vote{NodePubkey: detail.Vote, Amount: detail.VoteNumber})
+		}
+	}
+	return votes, nil
+}
+
+// createReceiver creates a new address of accountId and records it in the
+// account directory.
+func (s *ServerAdapter) createReceiver(ctx context.Context, accountId string) (*internal.ReceiverResp, error) {
+	resp := &internal.ReceiverResp{}
+	if err := s.call(ctx, "/create-account-receiver", &internal.AccountReq{AccountId: accountId}, resp, false); err != nil {
+		return nil, errors.Wrapf(err, "request create account receiver")
+	}
+
+	if s.directory != nil {
+		if err := s.directory.AddAddress(accountId, resp.Address); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -1,5 +1,7 @@
 package common
 
+import "time"
+
 const (
 	BTM  = "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
 	ETH  = "a0889e1080999e59ed552865a1d3ee677202796222141ccc3552041708aad76c"
@@ -7,8 +9,8 @@ const (
 )
 
 type TokenParam struct {
-	Code    string
-	Decimal uint8
+	Code    string `json:"code"`
+	Decimal uint8  `json:"decimal"`
 }
 
 var TokenParams = map[string]TokenParam{
@@ -16,3 +18,52 @@ var TokenParams = map[string]TokenParam{
 	ETH:  TokenParam{Code: "ETH", Decimal: 9},
 	USDT: TokenParam{Code: "USDT", Decimal: 6},
 }
+
+// UnknownAssetMode selects what an adapter does with an asset that is not in
+// its token registry.
+type UnknownAssetMode int
+
+const (
+	// UnknownAssetDrop leaves unknown assets out of the result.
+	UnknownAssetDrop UnknownAssetMode = iota
+	// UnknownAssetKeep returns unknown assets with their raw asset ID, no code
+	// or decimals and the Unknown flag set.
+	UnknownAssetKeep
+	// UnknownAssetLookup asks the node for the asset definition first and
+	// falls back to UnknownAssetKeep when it cannot be resolved, asking again
+	// after DefaultAssetRetryInterval at the earliest. Adapters
+	// without a node behave as with UnknownAssetKeep.
+	UnknownAssetLookup
+)
+
+// DefaultRequestTimeout bounds a single request to the vapor node when the
+// caller's context carries no deadline of its own.
+const DefaultRequestTimeout = 30 * time.Second
+
+// DefaultNodeRetryInterval is how long a node that failed a call is routed
+// after the healthy ones before it is tried first again.
+const DefaultNodeRetryInterval = 30 * time.Second
+
+// DefaultAssetRetryInterval is how long UnknownAssetLookup leaves an asset the
+// node could not resolve unknown before asking the node for it again.
+const DefaultAssetRetryInterval = 10 * time.Minute
+
+// DefaultMaxTxInputs and DefaultMaxTxOutputs bound the transactions built for
+// a batch payout. The input limit matches the UTXO count vapord merges into a
+// single transaction when it chains transactions.
+const (
+	DefaultMaxTxInputs  = 20
+	DefaultMaxTxOutputs = 100
+)
+
+// Input and output types of a UTXO, as named by the node.
+const (
+	UTXOSpend        = "spend"
+	UTXOCoinbase     = "coinbase"
+	UTXOCrossChainIn = "cross_chain_in"
+	UTXOVeto         = "veto"
+
+	UTXOControl       = "control"
+	UTXOCrossChainOut = "cross_chain_out"
+	UTXOVote          = "vote"
+)
@@ -1,9 +1,176 @@
 package common
 
-import "github.com/bytom/vapor/errors"
+import (
+	"net/http"
+	"strings"
+
+	"github.com/bytom/vapor/errors"
+
+	"vapor-adapter/internal"
+)
 
 var (
 	ErrBadLenXPubStr      = errors.New("bad length of pubkey key string")
 	ErrInvalidXPub        = errors.New("invalid xPub")
 	ErrInvalidAccessToken = errors.New("invalid access token")
+	ErrBadSigningInst     = errors.New("signing instruction references missing tx input")
+	ErrUnsupportedWitness = errors.New("unsupported witness component")
+	ErrNotEnoughSigs      = errors.New("not enough signatures to satisfy quorum")
+	ErrInvalidAddress     = errors.New("invalid address")
+	ErrNoPayments         = errors.New("at least one payment is required")
+	ErrInsufficientUTXO   = errors.New("unspent outputs are insufficient for payments and fee")
+	ErrProgramMismatch    = errors.New("control program does not match the account keys")
+	ErrOutputIDMismatch   = errors.New("output id does not match the unspent output")
+	ErrBadQuorum          = errors.New("quorum must be between 1 and the number of xpubs")
+	ErrDuplicateXPub      = errors.New("xpubs contain the same key more than once")
+	ErrBadDerivationIndex = errors.New("derivation indexes must fit in 32 bits and count must be positive")
+	ErrBadPayment         = errors.New("payment needs an address, an asset and a positive amount")
+	ErrTooManyInputs      = errors.New("transaction spends more inputs than allowed, merge the account's UTXOs first")
+	ErrFeeTooHigh         = errors.New("transaction fee exceeds the maximum of the fee policy")
+	ErrBadBuildOptions    = errors.New("invalid build options")
+	ErrBadVoteAmount      = errors.New("vote amount is below the minimum vote output amount")
+	ErrReorgTooDeep       = errors.New("chain reorganization is deeper than the scanner allows")
+	ErrAddressTaken       = errors.New("address is recorded for another account")
+)
+
+// Sentinel errors matched by a NodeError through errors.Is.
+var (
+	ErrNodeTimeout       = errors.New("node request timed out")
+	ErrBadRequest        = errors.New("invalid request body")
+	ErrUnauthenticated   = errors.New("request could not be authenticated")
+	ErrInsufficientFunds = errors.New("funds of account are insufficient")
+	ErrImmatureFunds     = errors.New("available funds of account are immature")
+	ErrReservedUTXO      = errors.New("available UTXOs of account have been reserved")
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrAssetNotFound     = errors.New("asset not found")
+	ErrDuplicateAlias    = errors.New("duplicate account alias")
+	ErrTxNotFound        = errors.New("transaction not found")
+	ErrOrphanTx          = errors.New("transaction input UTXO not found")
+	ErrTxFeeExceeded     = errors.New("transaction fee exceeded max limit")
+	ErrTxRejected        = errors.New("transaction rejected")
+	ErrDustTx            = errors.New("dust transaction")
+	ErrTxValidation      = errors.New("transaction failed validation")
+	ErrInsufficientTxFee = errors.New("transaction fee is insufficient")
+	ErrNodeInternal      = errors.New("node internal error")
+	ErrNodeUnavailable   = errors.New("node unavailable")
 )
+
+// nodeErrorCodes maps the chain codes of vapord to the sentinel they represent.
+var nodeErrorCodes = map[string]error{
+	"BTM000": ErrNodeInternal,
+	"BTM001": ErrNodeTimeout,
+	"BTM002": ErrBadRequest,
+	"BTM200": ErrBadQuorum,
+	"BTM203": ErrDuplicateXPub,
+	"BTM700": ErrInsufficientFunds,
+	"BTM701": ErrImmatureFunds,
+	"BTM702": ErrReservedUTXO,
+	"BTM705": ErrAccountNotFound,
+	"BTM706": ErrAssetNotFound,
+	"BTM716": ErrOrphanTx,
+	"BTM717": ErrTxFeeExceeded,
+	"BTM718": ErrTxRejected,
+	"BTM719": ErrDustTx,
+	"BTM770": ErrInsufficientTxFee,
+	"BTM860": ErrUnauthenticated,
+}
+
+// nodeErrorDetails maps error details of vapord that carry no dedicated chain
+// code to their sentinel.
+var nodeErrorDetails = map[string]error{
+	"Duplicate account alias":    ErrDuplicateAlias,
+	"No transaction(":            ErrTxNotFound,
+	"account TXID not found":     ErrTxNotFound,
+	"not existed in the mempool": ErrTxNotFound,
+}
+
+// NodeError is returned when the vapor node rejects a request, either with a
+// failed API response or with an HTTP status other than 200 OK.
+type NodeError struct {
+	Code       string
+	Message    string
+	Detail     string
+	HTTPStatus int
+	Endpoint   string
+}
+
+func (e *NodeError) Error() string {
+	var parts []string
+	if e.Code != "" {
+		parts = append(parts, e.Code)
+	}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+	if e.Detail != "" && e.Detail != e.Message {
+		parts = append(parts, e.Detail)
+	}
+	if len(parts) == 0 {
+		return http.StatusText(e.HTTPStatus)
+	}
+	return strings.Join(parts, ": ")
+}
+
+// Is reports whether the node error corresponds to the sentinel target, so
+// callers can branch with errors.Is instead of matching error strings.
+func (e *NodeError) Is(target error) bool {
+	switch {
+	case target == ErrNodeUnavailable:
+		return e.HTTPStatus >= http.StatusInternalServerError
+	case target == ErrTxValidation:
+		return e.Code >= "BTM730" && e.Code < "BTM790"
+	case nodeErrorCodes[e.Code] == target:
+		return true
+	}
+
+	for detail, sentinel := range nodeErrorDetails {
+		if sentinel == target && strings.Contains(e.Detail, detail) {
+			return true
+		}
+	}
+	return false
+}
+
+// SendStage names the step of a send pipeline that failed.
+type SendStage string
+
+const (
+	StageBuild  SendStage = "build"
+	StageSign   SendStage = "sign"
+	StageSubmit SendStage = "submit"
+)
+
+// SendError wraps the error of the stage a send pipeline failed at. A failure
+// before StageSubmit guarantees that nothing was broadcast.
+type SendError struct {
+	Stage SendStage
+	Err   error
+}
+
+func (e *SendError) Error() string {
+	return string(e.Stage) + " transaction: " + e.Err.Error()
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError is returned by a batch build that failed part way. The UTXOs of
+// every template it holds stay reserved by the node for the build TTL, or
+// until the template is submitted.
+type BatchError struct {
+	// Built are the templates built before the failure, in payment order.
+	Built []*internal.BuildTransactionResp
+	// Oversized holds the template of the failed build when the node picked
+	// more inputs for it than allowed.
+	Oversized []*internal.BuildTransactionResp
+	Err       error
+}
+
+func (e *BatchError) Error() string {
+	return "build batch: " + e.Err.Error()
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+
+	"github.com/bytom/vapor/errors"
+)
+
+// TokenRegistry is a concurrency safe set of known assets keyed by asset ID.
+// It can be filled from TokenParams, a JSON config file or the node, and
+// extended at runtime.
+type TokenRegistry struct {
+	mu     sync.RWMutex
+	tokens map[string]TokenParam
+}
+
+// NewTokenRegistry returns a registry holding the assets of TokenParams.
+func NewTokenRegistry() *TokenRegistry {
+	r := &TokenRegistry{tokens: make(map[string]TokenParam)}
+	for assetId, param := range TokenParams {
+		r.tokens[assetId] = param
+	}
+	return r
+}
+
+// LoadTokenRegistry returns a registry holding the assets of TokenParams plus
+// those of the JSON file at path, formatted as
+// {"<asset id>": {"code": "BTM", "decimal": 8}}.
+func LoadTokenRegistry(path string) (*TokenRegistry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read token config")
+	}
+
+	var tokens map[string]TokenParam
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, errors.Wrap(err, "decode token config")
+	}
+
+	r := NewTokenRegistry()
+	for assetId, param := range tokens {
+		r.Register(assetId, param)
+	}
+	return r, nil
+}
+
+// Get returns the parameters of assetId and whether it is known.
+func (r *TokenRegistry) Get(assetId string) (TokenParam, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	param, ok := r.tokens[assetId]
+	return param, ok
+}
+
+// Register adds or replaces the parameters of assetId.
+func (r *TokenRegistry) Register(assetId string, param TokenParam) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[assetId] = param
+}
+
+// Remove forgets assetId.
+func (r *TokenRegistry) Remove(assetId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.tokens, assetId)
+}
+
+// All returns a snapshot of every registered asset.
+func (r *TokenRegistry) All() map[string]TokenParam {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tokens := make(map[string]TokenParam, len(r.tokens))
+	for assetId, param := range r.tokens {
+		tokens[assetId] = param
+	}
+	return tokens
+}
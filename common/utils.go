@@ -2,9 +2,9 @@ package common
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"io/ioutil"
 	"net/http"
 )
@@ -15,7 +15,15 @@ func BasicAuth(username, password string) string {
 }
 
 func Post(url string, header map[string]string, payload []byte, result interface{}) error {
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+	return PostWithContext(context.Background(), nil, url, header, payload, result)
+}
+
+// PostWithContext is like Post but binds the request to ctx, so the call is
+// aborted as soon as ctx is cancelled or its deadline expires. The request is
+// sent through client, or through http.DefaultClient when client is nil, so
+// that connections are pooled across calls.
+func PostWithContext(ctx context.Context, client *http.Client, url string, header map[string]string, payload []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
 	if err != nil {
 		return err
 	}
@@ -25,7 +33,9 @@ func Post(url string, header map[string]string, payload []byte, result interface
 		req.Header.Set(k, v)
 	}
 
-	client := &http.Client{}
+	if client == nil {
+		client = http.DefaultClient
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -36,14 +46,35 @@ func Post(url string, header map[string]string, payload []byte, result interface
 		return nil
 	}
 
+	body, err := ioutil.ReadAll(resp.Body)
 	if resp.StatusCode != 200 {
-		return errors.New(http.StatusText(resp.StatusCode))
+		return newHTTPNodeError(url, resp.StatusCode, body)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
 
 	return json.Unmarshal(body, result)
 }
+
+// newHTTPNodeError builds the NodeError for a non-200 answer, keeping the chain
+// code and detail when the node sent its JSON error body.
+func newHTTPNodeError(url string, statusCode int, body []byte) *NodeError {
+	errResp := &struct {
+		Code    string `json:"code"`
+		Message string `json:"msg"`
+		Detail  string `json:"detail"`
+	}{}
+	if err := json.Unmarshal(body, errResp); err != nil || errResp.Message == "" {
+		errResp.Message = http.StatusText(statusCode)
+	}
+
+	return &NodeError{
+		Code:       errResp.Code,
+		Message:    errResp.Message,
+		Detail:     errResp.Detail,
+		HTTPStatus: statusCode,
+		Endpoint:   url,
+	}
+}
@@ -6,6 +6,7 @@ type GetUnconfirmedTxReq struct {
 
 type GetBlockReq struct {
 	BlockHeight uint64 `json:"block_height"`
+	BlockHash   string `json:"block_hash,omitempty"`
 }
 
 type GetTxReq struct {
@@ -18,28 +19,61 @@ type CreateAccountReq struct {
 	Alias     string   `json:"alias"`
 }
 
+type ListAccountsReq struct {
+	ID    string `json:"id,omitempty"`
+	Alias string `json:"alias,omitempty"`
+}
+
 type ListBalanceReq struct {
 	AccountId string `json:"account_id"`
 }
 
+type ListUnspentReq struct {
+	AccountId string `json:"account_id"`
+}
+
 type ListTxReq struct {
-	AccountId   string `json:"account_id"`
-	Detail      bool   `json:"detail"`
-	From        int    `json:"from"`
-	Count       int    `json:"count"`
+	AccountId string `json:"account_id"`
+	Detail    bool   `json:"detail"`
+	From      int    `json:"from"`
+	Count     int    `json:"count"`
+	StartTxId string `json:"start_tx_id,omitempty"`
 }
 
 type Actions struct {
-	AccountId string `json:"account_id,omitempty"`
-	Amount    uint64 `json:"amount"`
-	AssetId   string `json:"asset_id"`
-	Type      string `json:"type"`
-	Address   string `json:"address,omitempty"`
+	AccountId      string `json:"account_id,omitempty"`
+	Amount         uint64 `json:"amount"`
+	AssetId        string `json:"asset_id"`
+	Type           string `json:"type"`
+	Address        string `json:"address,omitempty"`
+	ControlProgram string `json:"control_program,omitempty"`
+	Vote           string `json:"vote,omitempty"`
 }
 
 type BuildTransactionReq struct {
 	BaseTransaction interface{} `json:"base_transaction"`
 	Actions         []*Actions  `json:"actions"`
 	TTL             int         `json:"ttl"`
-	TimeRange       int         `json:"time_range"`
+	TimeRange       uint64      `json:"time_range"`
+}
+
+type GetAssetReq struct {
+	ID string `json:"id"`
+}
+
+type SignTransactionReq struct {
+	Password    string                `json:"password"`
+	Transaction *BuildTransactionResp `json:"transaction"`
+}
+
+type SubmitTransactionReq struct {
+	RawTransaction string `json:"raw_transaction"`
+}
+
+type EstimateTxGasReq struct {
+	TxTemplate *BuildTransactionResp `json:"transaction_template"`
+}
+
+type AccountReq struct {
+	AccountId string `json:"account_id"`
 }
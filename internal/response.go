@@ -4,6 +4,8 @@ import "encoding/json"
 
 type Response struct {
 	Status    string          `json:"status"`
+	Code      string          `json:"code"`
+	Msg       string          `json:"msg"`
 	Data      json.RawMessage `json:"data"`
 	ErrDetail string          `json:"error_detail"`
 }
@@ -17,24 +19,39 @@ type ListUnconfirmedTxResp struct {
 }
 
 type Transaction struct {
-	ID        string `json:"id"`
-	TxId      string `json:"tx_id"`
-	BlockTime uint64 `json:"block_time"`
-	Inputs    []struct {
-		Address string `json:"address"`
-		Amount  uint64 `json:"amount"`
-		AssetId string `json:"asset_id"`
+	ID          string `json:"id"`
+	TxId        string `json:"tx_id"`
+	BlockTime   uint64 `json:"block_time"`
+	BlockHash   string `json:"block_hash"`
+	BlockHeight uint64 `json:"block_height"`
+	BlockIndex  uint32 `json:"block_index"`
+	StatusFail  bool   `json:"status_fail"`
+	Size        uint64 `json:"size"`
+	Inputs      []struct {
+		Type           string `json:"type"`
+		Address        string `json:"address"`
+		Amount         uint64 `json:"amount"`
+		AssetId        string `json:"asset_id"`
+		SpentOutputId  string `json:"spent_output_id"`
+		ControlProgram string `json:"control_program"`
 	} `json:"inputs"`
 	Outputs []struct {
-		Address string `json:"address"`
-		Amount  uint64 `json:"amount"`
-		AssetId string `json:"asset_id"`
+		Type           string `json:"type"`
+		OutputId       string `json:"id"`
+		Address        string `json:"address"`
+		Amount         uint64 `json:"amount"`
+		AssetId        string `json:"asset_id"`
+		ControlProgram string `json:"control_program"`
+		Vote           string `json:"vote"`
 	} `json:"outputs"`
 }
 
 type GetBlockResp struct {
-	Timestamp uint64         `json:"timestamp"`
-	Txs       []*Transaction `json:"transactions"`
+	Hash              string         `json:"hash"`
+	Height            uint64         `json:"height"`
+	PreviousBlockHash string         `json:"previous_block_hash"`
+	Timestamp         uint64         `json:"timestamp"`
+	Txs               []*Transaction `json:"transactions"`
 }
 
 type CreateAccountResp struct {
@@ -42,26 +59,93 @@ type CreateAccountResp struct {
 	AccountId string `json:"id"`
 }
 
+type Account struct {
+	ID     string   `json:"id"`
+	Alias  string   `json:"alias"`
+	XPubs  []string `json:"xpubs"`
+	Quorum int      `json:"quorum"`
+}
+
 type Balance struct {
 	Amount  uint64 `json:"amount"`
 	AssetId string `json:"asset_id"`
 }
 
+type UnspentOutput struct {
+	ID          string `json:"id"`
+	AccountId   string `json:"account_id"`
+	Address     string `json:"address"`
+	AssetId     string `json:"asset_id"`
+	Amount      uint64 `json:"amount"`
+	Change      bool   `json:"change"`
+	ValidHeight uint64 `json:"valid_height"`
+}
+
 type BuildTransactionResp struct {
 	RawTransaction      string                `json:"raw_transaction"`
 	SigningInstructions []SigningInstructions `json:"signing_instructions"`
+	Fee                 uint64                `json:"fee"`
+}
+
+type EstimateTxGasResp struct {
+	TotalNeu    int64 `json:"total_neu"`
+	FlexibleNeu int64 `json:"flexible_neu"`
+	StorageNeu  int64 `json:"storage_neu"`
+	VMNeu       int64 `json:"vm_neu"`
+}
+
+type SignTransactionResp struct {
+	Transaction  *BuildTransactionResp `json:"transaction"`
+	SignComplete bool                  `json:"sign_complete"`
+}
+
+type SubmitTransactionResp struct {
+	TxId string `json:"tx_id"`
 }
 
 type SigningInstructions struct {
-	Position          int `json:"position"`
-	WitnessComponents []struct {
-		Keys []struct {
-			DerivationPath []string `json:"derivation_path"`
-			Xpub           string   `json:"xpub"`
-		} `json:"keys,omitempty"`
-		Quorum     int         `json:"quorum,omitempty"`
-		Signatures interface{} `json:"signatures,omitempty"`
-		Type       string      `json:"type"`
-		Value      string      `json:"value,omitempty"`
-	} `json:"witness_components"`
+	Position          int                 `json:"position"`
+	WitnessComponents []*WitnessComponent `json:"witness_components"`
+}
+
+type WitnessComponent struct {
+	Keys       []*KeyID `json:"keys,omitempty"`
+	Quorum     int      `json:"quorum,omitempty"`
+	Signatures []string `json:"signatures,omitempty"`
+	Type       string   `json:"type"`
+	Value      string   `json:"value,omitempty"`
+}
+
+type KeyID struct {
+	DerivationPath []string `json:"derivation_path"`
+	Xpub           string   `json:"xpub"`
+}
+
+type NetInfoResp struct {
+	Listening    bool   `json:"listening"`
+	Syncing      bool   `json:"syncing"`
+	PeerCount    int    `json:"peer_count"`
+	CurrentBlock uint64 `json:"current_block"`
+	HighestBlock uint64 `json:"highest_block"`
+	NetworkId    string `json:"network_id"`
+}
+
+type Asset struct {
+	ID         string          `json:"id"`
+	Alias      string          `json:"alias"`
+	Definition json.RawMessage `json:"definition"`
+}
+
+type ReceiverResp struct {
+	ControlProgram string `json:"control_program"`
+	Address        string `json:"address"`
+}
+
+type AccountVotes struct {
+	AccountId       string `json:"account_id"`
+	TotalVoteNumber uint64 `json:"total_vote_number"`
+	VoteDetails     []struct {
+		Vote       string `json:"vote"`
+		VoteNumber uint64 `json:"vote_number"`
+	} `json:"vote_details"`
 }
@@ -1,19 +1,50 @@
 package types
 
+// UTXO is an input or output of a transaction. Type is one of the common.UTXO
+// input or output types. OutputID is set on outputs, SpentOutputID on the
+// inputs spending an output, and Index is the position in the transaction.
+// Vote is the consensus node public key a vote output votes for, or a veto
+// input decoded from a raw transaction withdraws from.
 type UTXO struct {
 	Address         string `json:"address,omitempty"`
 	Value           uint64 `json:"value,omitempty"`
 	TokenIdentifier string `json:"token_identifier,omitempty"`
 	TokenCode       string `json:"token_code,omitempty"`
 	TokenDecimal    uint8  `json:"token_decimal,omitempty"`
+	Unknown         bool   `json:"unknown,omitempty"`
+	Type            string `json:"type,omitempty"`
+	Vote            string `json:"vote,omitempty"`
+	OutputID        string `json:"output_id,omitempty"`
+	SpentOutputID   string `json:"spent_output_id,omitempty"`
+	Index           int    `json:"index"`
+	ControlProgram  string `json:"control_program,omitempty"`
 }
 
+// Tx is a transaction. The block fields and Confirmations are zero for a
+// transaction still in the mempool. Fee is the amount of every asset the
+// inputs spend beyond the outputs.
 type Tx struct {
-	TxHash  string            `json:"tx_hash,omitempty"`
-	Inputs  []*UTXO           `json:"inputs"`
-	Outputs []*UTXO           `json:"outputs"`
-	TxAt    uint64            `json:"tx_at,omitempty"`
-	Extra   map[string]string `json:"extra"`
+	TxHash        string            `json:"tx_hash,omitempty"`
+	Inputs        []*UTXO           `json:"inputs"`
+	Outputs       []*UTXO           `json:"outputs"`
+	TxAt          uint64            `json:"tx_at,omitempty"`
+	BlockHeight   uint64            `json:"block_height,omitempty"`
+	BlockHash     string            `json:"block_hash,omitempty"`
+	Position      uint32            `json:"position,omitempty"`
+	Fee           map[string]uint64 `json:"fee,omitempty"`
+	StatusFail    bool              `json:"status_fail"`
+	Size          uint64            `json:"size,omitempty"`
+	Confirmations uint64            `json:"confirmations"`
+	Extra         map[string]string `json:"extra"`
+}
+
+// Block is a block of the chain with its transactions.
+type Block struct {
+	Height       uint64 `json:"height"`
+	Hash         string `json:"hash"`
+	PreviousHash string `json:"previous_hash"`
+	Timestamp    uint64 `json:"timestamp"`
+	Txs          []*Tx  `json:"txs"`
 }
 
 type Balance struct {
@@ -21,4 +52,35 @@ type Balance struct {
 	TokenIdentifier string `json:"token_identifier"`
 	TokenDecimal    uint8  `json:"token_decimal"`
 	Balance         uint64 `json:"balance"`
+	Unknown         bool   `json:"unknown,omitempty"`
+}
+
+// Unspent is an unspent output controlled by an HD account, as consumed by
+// ClientAdapter.BuildTransaction. RootXPubs, Quorum and the indexes describe
+// the account keys the output was derived from and must match ControlProgram.
+type Unspent struct {
+	OutputID       string   `json:"output_id,omitempty"`
+	SourceID       string   `json:"source_id"`
+	SourcePos      uint64   `json:"source_pos"`
+	ControlProgram string   `json:"control_program"`
+	AssetID        string   `json:"asset_id"`
+	Amount         uint64   `json:"amount"`
+	RootXPubs      []string `json:"root_xpubs"`
+	Quorum         int      `json:"quorum"`
+	AccountIndex   uint64   `json:"account_index"`
+	AddressIndex   uint64   `json:"address_index"`
+	Change         bool     `json:"change"`
+}
+
+// Payment sends Amount of the asset AssetID to Address.
+type Payment struct {
+	Address string `json:"address"`
+	AssetID string `json:"asset_id"`
+	Amount  uint64 `json:"amount"`
+}
+
+// Vote is the BTM an account votes for a consensus node.
+type Vote struct {
+	NodePubkey string `json:"node_pubkey"`
+	Amount     uint64 `json:"amount"`
 }